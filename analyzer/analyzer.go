@@ -0,0 +1,184 @@
+// Package analyzer analyzes single resumes for services that embed the resume analyzer, e.g.
+// an applicant tracking system. Resumes are passed as readers and the result is a typed
+// candidate profile; nothing is written to disk except a temporary copy of the PDF for OCR.
+//
+//	a := analyzer.New(analyzer.Options{})
+//	profile, err := a.Analyze(ctx, pdf)
+//
+// By default AWS Textract and Bedrock are used with the standard AWS configuration. Any
+// OCRService and LLMService can be passed instead, including the wrappers in modules/, e.g.
+// redact.NewRedactingService to keep personal data out of the prompts. The context passed to
+// Analyze is passed on to every LLM call, described with interfaces.WithCall.
+package analyzer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/nicoalimin/resume-analyzer/interfaces"
+	"github.com/nicoalimin/resume-analyzer/modules/llm/bedrock"
+	"github.com/nicoalimin/resume-analyzer/modules/ocr/textract"
+	"github.com/nicoalimin/resume-analyzer/modules/pdf/links"
+	"github.com/nicoalimin/resume-analyzer/pipeline"
+	"github.com/nicoalimin/resume-analyzer/prompts"
+)
+
+// Options configures an Analyzer. All fields are optional.
+type Options struct {
+	// OCR extracts the text from PDFs (default AWS Textract)
+	OCR interfaces.OCRService
+	// LLM extracts the candidate profile (default AWS Bedrock)
+	LLM interfaces.LLMService
+	// PhoneCountryCode is used to convert phone numbers without an international prefix to E.164
+	PhoneCountryCode string
+	// ContactFallback asks the LLM for contact details that pattern matching did not find
+	ContactFallback bool
+	// Summarize also generates a written summary of the resume, which costs one more LLM call
+	Summarize bool
+}
+
+// Profile is the candidate profile extracted from a resume
+type Profile struct {
+	pipeline.ApplicantRecord
+	// Summary is the written summary of the resume, if Options.Summarize is set
+	Summary string `json:"summary,omitempty"`
+	// Links are the targets of the hyperlinks in the PDF
+	Links []string `json:"links"`
+	// Warnings are problems that did not stop the analysis, e.g. unreadable hyperlinks
+	Warnings []string `json:"warnings,omitempty"`
+	// Text is the resume text the profile was extracted from
+	Text string `json:"-"`
+}
+
+// Analyzer extracts candidate profiles from resumes. It is safe for concurrent use if the
+// services it uses are.
+type Analyzer struct {
+	ocr  interfaces.OCRService
+	llm  interfaces.LLMService
+	opts Options
+}
+
+// New returns an Analyzer using the given options
+func New(opts Options) *Analyzer {
+	a := &Analyzer{ocr: opts.OCR, llm: opts.LLM, opts: opts}
+	if a.ocr == nil {
+		a.ocr = textract.NewTextractService()
+	}
+	if a.llm == nil {
+		a.llm = bedrock.NewBedrockService()
+	}
+	return a
+}
+
+// Analyze extracts the text of a PDF resume with the OCR service and returns the candidate
+// profile. The context is checked between the OCR and LLM calls; calls that have already
+// started run to completion.
+func (a *Analyzer) Analyze(ctx context.Context, pdf io.Reader) (*Profile, error) {
+	return a.AnalyzeFile(ctx, "", pdf)
+}
+
+// AnalyzeFile is like Analyze for a resume with a known file name. The name is recorded as
+// the profile's source file, and the name without extension is used as the candidate key and
+// as the candidate name if none is found in the resume.
+func (a *Analyzer) AnalyzeFile(ctx context.Context, name string, pdf io.Reader) (*Profile, error) {
+	// The OCR service and the hyperlink reader work on files
+	file, err := os.CreateTemp("", "resume-*.pdf")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(file.Name())
+	_, err = io.Copy(file, pdf)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF: %w", err)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	text, err := a.ocr.ExtractTextFromPDF(file.Name())
+	if err != nil {
+		return nil, fmt.Errorf("OCR failed: %w", err)
+	}
+
+	var warnings []string
+	pdfLinks, err := links.ExtractLinks(file.Name())
+	if err != nil {
+		warnings = append(warnings, fmt.Sprintf("failed to read links: %v", err))
+	}
+	return a.analyze(ctx, name, text, pdfLinks, warnings)
+}
+
+// AnalyzeText returns the candidate profile for a resume that is already available as text.
+// Profile links are only found if they are spelled out in the text.
+func (a *Analyzer) AnalyzeText(ctx context.Context, text io.Reader) (*Profile, error) {
+	content, err := io.ReadAll(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read text: %w", err)
+	}
+	return a.analyze(ctx, "", string(content), nil, nil)
+}
+
+func (a *Analyzer) analyze(ctx context.Context, name string, text string, pdfLinks []string, warnings []string) (*Profile, error) {
+	if strings.TrimSpace(text) == "" {
+		return nil, errors.New("the resume contains no text")
+	}
+
+	key := ""
+	if name != "" {
+		key = pipeline.CandidateBaseName(name)
+	}
+	env := pipeline.Env{
+		Context:          ctx,
+		PhoneCountryCode: a.opts.PhoneCountryCode,
+		Progress: func(event pipeline.Event) {
+			if event.Kind == pipeline.EventWarning {
+				warnings = append(warnings, event.Err.Error())
+			}
+		},
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	applicant, err := pipeline.ExtractDocument(pipeline.DocumentOptions{
+		Env:             env,
+		LLM:             a.llm,
+		ContactFallback: a.opts.ContactFallback,
+	}, key, text, pdfLinks)
+	if err != nil {
+		return nil, err
+	}
+
+	applicant.SourceFile = name
+	profile := &Profile{
+		ApplicantRecord: pipeline.NewApplicantRecord(applicant, time.Now()),
+		Links:           pdfLinks,
+		Text:            text,
+	}
+	if profile.Links == nil {
+		profile.Links = []string{}
+	}
+
+	if a.opts.Summarize {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		call := interfaces.CallFrom(ctx)
+		call.Candidates, call.Texts = []string{key}, []string{text}
+		profile.Summary, err = interfaces.GenerateTextContext(interfaces.WithCall(ctx, call), a.llm, prompts.GetSummaryPrompt(text))
+		if err != nil {
+			return nil, fmt.Errorf("summary failed: %w", err)
+		}
+	}
+
+	profile.Warnings = warnings
+	return profile, nil
+}
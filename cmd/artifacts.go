@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/nicoalimin/resume-analyzer/modules/crypto/envelope"
+	"github.com/spf13/viper"
+)
+
+var artifactCipher *envelope.Cipher
+var artifactCipherErr error
+var artifactCipherOnce sync.Once
+
+// loadArtifactCipher returns the cipher for the configured encryption key, or nil if
+// encryption is not enabled. The key is read from encryption_key (e.g. the ENCRYPTION_KEY
+// environment variable) or from the file named by encryption_key_file.
+func loadArtifactCipher() (*envelope.Cipher, error) {
+	artifactCipherOnce.Do(func() {
+		encoded := viper.GetString("encryption_key")
+		if path := viper.GetString("encryption_key_file"); encoded == "" && path != "" {
+			content, err := os.ReadFile(path)
+			if err != nil {
+				artifactCipherErr = fmt.Errorf("failed to read encryption key file: %w", err)
+				return
+			}
+			encoded = string(content)
+		}
+		if encoded == "" {
+			return
+		}
+
+		key, err := envelope.ParseKey(encoded)
+		if err != nil {
+			artifactCipherErr = err
+			return
+		}
+		artifactCipher, artifactCipherErr = envelope.NewCipher(key)
+	})
+	return artifactCipher, artifactCipherErr
+}
+
+// readArtifact reads a file written by the pipeline, decrypting it if needed
+func readArtifact(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil || !envelope.IsEncrypted(data) {
+		return data, err
+	}
+
+	c, err := loadArtifactCipher()
+	if err != nil {
+		return nil, err
+	}
+	if c == nil {
+		return nil, fmt.Errorf("%s: %w", path, envelope.ErrEncrypted)
+	}
+	plaintext, err := c.Decrypt(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return plaintext, nil
+}
+
+// writeArtifact writes a pipeline file, encrypted if an encryption key is configured
+func writeArtifact(path string, data []byte, perm os.FileMode) error {
+	c, err := loadArtifactCipher()
+	if err != nil {
+		return err
+	}
+	if c != nil {
+		if data, err = c.Encrypt(data); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(path, data, perm)
+}
+
+// artifactFiles gives the pipeline package access to the files through readArtifact and writeArtifact
+type artifactFiles struct{}
+
+func (artifactFiles) ReadFile(path string) ([]byte, error) {
+	return readArtifact(path)
+}
+
+func (artifactFiles) WriteFile(path string, data []byte, perm os.FileMode) error {
+	return writeArtifact(path, data, perm)
+}
@@ -0,0 +1,348 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/nicoalimin/resume-analyzer/interfaces"
+	"github.com/nicoalimin/resume-analyzer/modules/llm/bedrock"
+	"github.com/nicoalimin/resume-analyzer/modules/llm/redact"
+	"github.com/nicoalimin/resume-analyzer/pipeline"
+	"github.com/nicoalimin/resume-analyzer/prompts"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var auditInputDir string
+var auditOutputFile string
+var auditNames []string
+var auditYearShift int
+var auditLLMService interfaces.LLMService
+
+// seniorityRanks turns the seniority levels of the extraction prompt into comparable numbers
+var seniorityRanks = map[string]float64{
+	"junior": 1, "mid": 2, "senior": 3, "lead": 4, "manager": 5, "director": 6, "vp": 7, "c-level": 8,
+}
+
+// genderSwaps maps gendered words to their counterpart. "her" is ambiguous and becomes "his".
+var genderSwaps = map[string]string{
+	"he": "she", "she": "he", "him": "her", "her": "his", "his": "her", "hers": "his",
+	"himself": "herself", "herself": "himself", "mr": "ms", "ms": "mr", "mrs": "mr",
+	"male": "female", "female": "male", "man": "woman", "woman": "man",
+}
+var genderWordPattern = regexp.MustCompile(`(?i)\b(he|she|him|her|his|hers|himself|herself|mr|ms|mrs|male|female|man|woman)\b`)
+
+var educationLinePattern = regexp.MustCompile(`(?i)university|college|institute|polytechnic|school|academy|bachelor|master|degree|diploma|graduat|b\.?sc|m\.?sc|b\.?eng|ph\.?d|mba|class of`)
+var fourDigitYearPattern = regexp.MustCompile(`\b(19|20)\d\d\b`)
+
+// auditScores are the numeric values compared between the original and a perturbed resume
+type auditScores struct {
+	Seniority  float64 `json:"seniority"`
+	YearsOfExp float64 `json:"years_of_exp"`
+	Skills     float64 `json:"skills"`
+}
+
+// auditVariant is one perturbed copy of a resume
+type auditVariant struct {
+	Attribute string
+	Label     string
+	text      string
+}
+
+// auditResult holds the scores of one variant and how far they moved from the original
+type auditResult struct {
+	Candidate string      `json:"candidate"`
+	Attribute string      `json:"attribute"`
+	Label     string      `json:"label"`
+	Scores    auditScores `json:"scores"`
+	Delta     auditScores `json:"delta"`
+}
+
+// auditSummary aggregates the deltas of all candidates for one attribute
+type auditSummary struct {
+	Attribute        string      `json:"attribute"`
+	Runs             int         `json:"runs"`
+	MeanDelta        auditScores `json:"mean_delta"`
+	MeanAbsDelta     auditScores `json:"mean_abs_delta"`
+	SeniorityChanged int         `json:"seniority_changed"`
+}
+
+// auditCmd represents the audit command
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Check whether screening results change when names, pronouns or graduation years change",
+	Long: `Runs the direct extraction on every resume in a folder and then on perturbed copies of it:
+with the applicant's name replaced, with gendered pronouns swapped and with graduation years
+shifted. The seniority level, years of experience and number of skills of each copy are compared
+with the original and the deltas are reported per attribute.
+
+An unchanged copy is run as well, so that deltas caused by bias can be told apart from the
+model's normal run-to-run variation.`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		// Initialize LLM service if not already set
+		if auditLLMService == nil {
+			auditLLMService = bedrock.NewBedrockService()
+		}
+		// Every run, including the control run, must reach the model: a cached answer would
+		// hide the run-to-run variation and repeat the scores of an earlier audit
+		viper.Set("no_cache", true)
+		var err error
+		auditLLMService, err = wrapLLMService(auditLLMService)
+		return setupError(cmd, err)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if auditInputDir == "" {
+			return errors.New("input directory must be specified with --input")
+		}
+		if viper.GetBool("redact") {
+			fmt.Fprintln(os.Stderr, "Warning: with redaction enabled the model never sees the names, so name variants show no effect.")
+		}
+
+		files, err := os.ReadDir(auditInputDir)
+		if err != nil {
+			return setupError(cmd, fmt.Errorf("failed to read input directory: %w", err))
+		}
+
+		var results []auditResult
+		for _, file := range files {
+			if file.IsDir() || !strings.HasSuffix(file.Name(), ".txt") {
+				continue
+			}
+			baseName := strings.TrimSuffix(file.Name(), ".txt")
+
+			content, err := readArtifact(filepath.Join(auditInputDir, file.Name()))
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to read %s: %v\n", file.Name(), err)
+				continue
+			}
+
+			fmt.Printf("Auditing %s...\n", file.Name())
+			original, err := auditRun(baseName, string(content))
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Bedrock failed for %s: %v\n", file.Name(), err)
+				continue
+			}
+
+			for _, variant := range auditVariants(string(content), auditNames, auditYearShift) {
+				scores, err := auditRun(baseName, variant.text)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Bedrock failed for %s (%s): %v\n", file.Name(), variant.Label, err)
+					continue
+				}
+				result := auditResult{
+					Candidate: baseName,
+					Attribute: variant.Attribute,
+					Label:     variant.Label,
+					Scores:    scores,
+					Delta: auditScores{
+						Seniority:  scores.Seniority - original.Seniority,
+						YearsOfExp: scores.YearsOfExp - original.YearsOfExp,
+						Skills:     scores.Skills - original.Skills,
+					},
+				}
+				results = append(results, result)
+				storeScore(baseName, "audit:"+variant.Label+":seniority_delta", result.Delta.Seniority)
+				storeScore(baseName, "audit:"+variant.Label+":years_of_exp_delta", result.Delta.YearsOfExp)
+				storeScore(baseName, "audit:"+variant.Label+":skills_delta", result.Delta.Skills)
+			}
+		}
+
+		if len(results) == 0 {
+			return setupError(cmd, errors.New("no resumes could be audited"))
+		}
+
+		summaries := summarizeAudit(results)
+		printAudit(results, summaries)
+
+		if auditOutputFile != "" {
+			data, err := json.MarshalIndent(map[string]any{"summary": summaries, "results": results}, "", "  ")
+			if err != nil {
+				return setupError(cmd, fmt.Errorf("failed to encode audit report: %w", err))
+			}
+			if err := writeArtifact(auditOutputFile, data, 0644); err != nil {
+				return setupError(cmd, fmt.Errorf("failed to write audit report: %w", err))
+			}
+			fmt.Printf("Audit report saved to %s\n", auditOutputFile)
+		}
+		return nil
+	},
+}
+
+// SetAuditLLMService allows dependency injection of LLM service (useful for testing)
+func SetAuditLLMService(service interfaces.LLMService) {
+	auditLLMService = service
+}
+
+// auditRun extracts the applicant data from a resume of candidate key and returns its scores.
+// The text goes with the call, so with redaction the name of each variant is redacted too.
+func auditRun(key string, text string) (auditScores, error) {
+	ctx := interfaces.WithCall(context.Background(), interfaces.Call{Candidates: []string{key}, Texts: []string{text}})
+	response, err := interfaces.GenerateTextContext(ctx, auditLLMService, prompts.GetDirectExtractionPrompt(text))
+	if err != nil {
+		return auditScores{}, err
+	}
+	applicant := pipeline.ParseApplicantJSON(response)
+
+	scores := auditScores{
+		Seniority: seniorityRanks[strings.ToLower(strings.TrimSpace(applicant.Seniority))],
+		Skills:    float64(len(pipeline.SplitSkillset(applicant.Skillset))),
+	}
+	if years := pipeline.ParseYearsOfExp(applicant.YearsOfExp); years != nil {
+		scores.YearsOfExp = *years
+	}
+	return scores, nil
+}
+
+// auditVariants returns the perturbed copies of a resume. Variants that would be identical
+// to the original, e.g. a pronoun swap on a resume without pronouns, are left out.
+func auditVariants(text string, names []string, yearShift int) []auditVariant {
+	variants := []auditVariant{{Attribute: "control", Label: "unchanged", text: text}}
+
+	if name := redact.ApplicantName(text); name != "" {
+		for _, replacement := range names {
+			if strings.TrimSpace(replacement) == "" || strings.EqualFold(replacement, name) {
+				continue
+			}
+			variants = append(variants, auditVariant{
+				Attribute: "name",
+				Label:     "name=" + replacement,
+				text:      swapName(text, name, replacement),
+			})
+		}
+	}
+
+	if swapped := swapGender(text); swapped != text {
+		variants = append(variants, auditVariant{Attribute: "gender", Label: "pronouns swapped", text: swapped})
+	}
+
+	if shifted := shiftGraduationYears(text, yearShift); shifted != text {
+		variants = append(variants, auditVariant{
+			Attribute: "age",
+			Label:     fmt.Sprintf("graduation %+d years", yearShift),
+			text:      shifted,
+		})
+	}
+
+	return variants
+}
+
+// swapName replaces the full name and the first and last name on their own
+func swapName(text string, name string, replacement string) string {
+	text = regexp.MustCompile(`(?i)\b`+regexp.QuoteMeta(name)+`\b`).ReplaceAllString(text, replacement)
+
+	parts, replacementParts := strings.Fields(name), strings.Fields(replacement)
+	pairs := [][2]string{
+		{parts[0], replacementParts[0]},
+		{parts[len(parts)-1], replacementParts[len(replacementParts)-1]},
+	}
+	for _, pair := range pairs {
+		if len(pair[0]) < 3 {
+			continue
+		}
+		text = regexp.MustCompile(`\b`+regexp.QuoteMeta(pair[0])+`\b`).ReplaceAllString(text, pair[1])
+	}
+	return text
+}
+
+// swapGender exchanges gendered pronouns, titles and nouns
+func swapGender(text string) string {
+	return genderWordPattern.ReplaceAllStringFunc(text, func(word string) string {
+		return pipeline.MatchCase(genderSwaps[strings.ToLower(word)], word)
+	})
+}
+
+// shiftGraduationYears moves every year on an education line by shift years
+func shiftGraduationYears(text string, shift int) string {
+	if shift == 0 {
+		return text
+	}
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if !educationLinePattern.MatchString(line) {
+			continue
+		}
+		lines[i] = fourDigitYearPattern.ReplaceAllStringFunc(line, func(year string) string {
+			value, _ := strconv.Atoi(year)
+			return strconv.Itoa(value + shift)
+		})
+	}
+	return strings.Join(lines, "\n")
+}
+
+// summarizeAudit aggregates the deltas per attribute
+func summarizeAudit(results []auditResult) []auditSummary {
+	byAttribute := map[string]*auditSummary{}
+	var order []string
+	for _, result := range results {
+		summary, ok := byAttribute[result.Attribute]
+		if !ok {
+			summary = &auditSummary{Attribute: result.Attribute}
+			byAttribute[result.Attribute] = summary
+			order = append(order, result.Attribute)
+		}
+		summary.Runs++
+		summary.MeanDelta.Seniority += result.Delta.Seniority
+		summary.MeanDelta.YearsOfExp += result.Delta.YearsOfExp
+		summary.MeanDelta.Skills += result.Delta.Skills
+		summary.MeanAbsDelta.Seniority += math.Abs(result.Delta.Seniority)
+		summary.MeanAbsDelta.YearsOfExp += math.Abs(result.Delta.YearsOfExp)
+		summary.MeanAbsDelta.Skills += math.Abs(result.Delta.Skills)
+		if result.Delta.Seniority != 0 {
+			summary.SeniorityChanged++
+		}
+	}
+
+	summaries := make([]auditSummary, 0, len(order))
+	for _, attribute := range order {
+		summary := byAttribute[attribute]
+		n := float64(summary.Runs)
+		summary.MeanDelta = auditScores{summary.MeanDelta.Seniority / n, summary.MeanDelta.YearsOfExp / n, summary.MeanDelta.Skills / n}
+		summary.MeanAbsDelta = auditScores{summary.MeanAbsDelta.Seniority / n, summary.MeanAbsDelta.YearsOfExp / n, summary.MeanAbsDelta.Skills / n}
+		summaries = append(summaries, *summary)
+	}
+	// The control row goes first since the other rows are read against it
+	sort.SliceStable(summaries, func(i, j int) bool {
+		return summaries[i].Attribute == "control" && summaries[j].Attribute != "control"
+	})
+	return summaries
+}
+
+func printAudit(results []auditResult, summaries []auditSummary) {
+	fmt.Println("\nDeltas per variant (variant minus original):")
+	fmt.Printf("%-30s %-32s %10s %10s %8s\n", "Candidate", "Variant", "Seniority", "Years", "Skills")
+	for _, result := range results {
+		fmt.Printf("%-30s %-32s %+10.1f %+10.1f %+8.0f\n",
+			result.Candidate, result.Label, result.Delta.Seniority, result.Delta.YearsOfExp, result.Delta.Skills)
+	}
+
+	fmt.Println("\nSummary per attribute (mean delta / mean absolute delta):")
+	fmt.Printf("%-10s %6s %18s %18s %18s %18s\n", "Attribute", "Runs", "Seniority", "Years", "Skills", "Seniority changed")
+	for _, summary := range summaries {
+		fmt.Printf("%-10s %6d %+8.2f / %7.2f %+8.2f / %7.2f %+8.2f / %7.2f %18s\n",
+			summary.Attribute, summary.Runs,
+			summary.MeanDelta.Seniority, summary.MeanAbsDelta.Seniority,
+			summary.MeanDelta.YearsOfExp, summary.MeanAbsDelta.YearsOfExp,
+			summary.MeanDelta.Skills, summary.MeanAbsDelta.Skills,
+			fmt.Sprintf("%d of %d", summary.SeniorityChanged, summary.Runs))
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(auditCmd)
+	auditCmd.Flags().StringVarP(&auditInputDir, "input", "i", "", "Input folder containing .txt files")
+	auditCmd.Flags().StringVarP(&auditOutputFile, "output", "o", "", "Write the full audit report as JSON to this file (optional)")
+	auditCmd.Flags().StringSliceVar(&auditNames, "names", []string{"Emily Walsh", "Greg Baker", "Lakisha Washington", "Wei Chen", "Mohammed Rahman"}, "Replacement names to try, one variant each")
+	auditCmd.Flags().IntVar(&auditYearShift, "year-shift", -15, "Number of years to move graduation years by")
+}
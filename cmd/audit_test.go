@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nicoalimin/resume-analyzer/interfaces"
+	"github.com/nicoalimin/resume-analyzer/modules/llm/redact"
+)
+
+// promptRecorder answers every prompt with an empty extraction and keeps the prompts
+type promptRecorder struct {
+	prompts []string
+}
+
+func (p *promptRecorder) GenerateText(prompt string) (string, error) {
+	text, _, err := p.GenerateTextWithUsage(context.Background(), prompt)
+	return text, err
+}
+
+func (p *promptRecorder) GenerateTextWithUsage(ctx context.Context, prompt string) (string, interfaces.TokenUsage, error) {
+	p.prompts = append(p.prompts, prompt)
+	return `{"name": "N/A", "seniority": "Senior"}`, interfaces.TokenUsage{}, nil
+}
+
+func TestAuditRunRedactsNameVariants(t *testing.T) {
+	r, err := redact.NewRedactor(artifactFiles{}, filepath.Join(t.TempDir(), "redaction-map.json"), redact.DefaultPatterns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder := &promptRecorder{}
+	previous := auditLLMService
+	auditLLMService = redact.NewRedactingService(recorder, r)
+	defer func() { auditLLMService = previous }()
+
+	resume := "Jane Doe\nSenior engineer. Jane led the platform team at Acme."
+	for _, variant := range auditVariants(resume, []string{"Lakisha Washington"}, 0) {
+		if _, err := auditRun("jane_doe", variant.text); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(recorder.prompts) != 2 {
+		t.Fatalf("got %d calls, want the control and the name variant", len(recorder.prompts))
+	}
+	for _, prompt := range recorder.prompts {
+		for _, name := range []string{"Jane", "Lakisha", "Washington"} {
+			if strings.Contains(prompt, name) {
+				t.Errorf("prompt contains %q:\n%s", name, prompt)
+			}
+		}
+	}
+}
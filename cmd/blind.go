@@ -0,0 +1,20 @@
+package cmd
+
+import (
+	"github.com/nicoalimin/resume-analyzer/pipeline"
+	"github.com/spf13/viper"
+)
+
+const defaultBlindMapping = "blind-map.json"
+
+func blindMappingPath() string {
+	if path := viper.GetString("blind_mapping"); path != "" {
+		return path
+	}
+	return defaultBlindMapping
+}
+
+// loadBlindMapping reads the configured blind mapping file
+func loadBlindMapping() (*pipeline.BlindMapping, error) {
+	return pipeline.LoadBlindMapping(artifactFiles{}, blindMappingPath())
+}
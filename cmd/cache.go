@@ -0,0 +1,83 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+const defaultCacheDir = ".resume-analyzer-cache"
+const defaultCacheTTL = 7 * 24 * time.Hour
+
+var cacheClearExpired bool
+
+// cacheCmd represents the cache command
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Inspect or clear the cache of LLM and OCR responses",
+	Long: `LLM responses are cached by model ID, generation parameters and prompt, and OCR results by
+the hash of the PDF, so re-running a command over the same files does not pay for the same
+calls twice. Entries expire after cache_ttl (default 168h) and are stored in cache_dir
+(default .resume-analyzer-cache). Use --no-cache on any command to bypass the cache.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+var cacheStatsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show the number and size of cached responses",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := newCacheStore()
+		if err != nil {
+			return setupError(cmd, err)
+		}
+		stats, err := store.Stats()
+		if err != nil {
+			return setupError(cmd, fmt.Errorf("failed to read cache: %w", err))
+		}
+		if len(stats) == 0 {
+			fmt.Println("The cache is empty.")
+			return nil
+		}
+
+		kinds := make([]string, 0, len(stats))
+		for kind := range stats {
+			kinds = append(kinds, kind)
+		}
+		sort.Strings(kinds)
+		for _, kind := range kinds {
+			s := stats[kind]
+			fmt.Printf("%-4s %6d entries  %6d expired  %8.1f KB\n", kind, s.Entries, s.Expired, float64(s.Bytes)/1024)
+		}
+		return nil
+	},
+}
+
+var cacheClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Remove cached responses",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := newCacheStore()
+		if err != nil {
+			return setupError(cmd, err)
+		}
+		removed, err := store.Clear(cacheClearExpired)
+		if err != nil {
+			return setupError(cmd, fmt.Errorf("failed to clear cache: %w", err))
+		}
+		fmt.Printf("Removed %d cached responses.\n", removed)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(cacheCmd)
+	cacheCmd.AddCommand(cacheStatsCmd, cacheClearCmd)
+	cacheClearCmd.Flags().BoolVar(&cacheClearExpired, "expired", false, "Only remove expired and unreadable entries")
+}
@@ -1,11 +1,12 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
-	"os"
 
 	"github.com/nicoalimin/resume-analyzer/interfaces"
 	"github.com/nicoalimin/resume-analyzer/modules/ocr/textract"
+	"github.com/nicoalimin/resume-analyzer/pipeline"
 	"github.com/spf13/cobra"
 )
 
@@ -16,45 +17,33 @@ var ocrService interfaces.OCRService
 var convertPDFsCmd = &cobra.Command{
 	Use:   "convert-pdfs",
 	Short: "Convert PDFs in a folder to text using AWS Textract",
-	Long:  `Processes all PDFs in a folder using AWS Textract and saves the extracted text to another folder.`,
-	PreRun: func(cmd *cobra.Command, args []string) {
+	Long: `Processes all PDFs in a folder using AWS Textract and saves the extracted text to another folder.
+The targets of hyperlinks in each PDF are saved next to the text as <name>.links.json.`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
 		// Initialize OCR service if not already set
 		if ocrService == nil {
 			ocrService = textract.NewTextractService()
 		}
+		var err error
+		ocrService, err = wrapOCRService(ocrService)
+		return setupError(cmd, err)
 	},
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		if convertInputDir == "" || convertOutputDir == "" {
-			fmt.Fprintln(os.Stderr, "Both --input and --output folders must be specified.")
-			os.Exit(1)
+			return errors.New("both --input and --output folders must be specified")
 		}
 
-		files, err := os.ReadDir(convertInputDir)
+		_, err := pipeline.Convert(pipeline.ConvertOptions{
+			Env:       pipelineEnv("Processing", ""),
+			OCR:       ocrService,
+			InputDir:  convertInputDir,
+			OutputDir: convertOutputDir,
+		})
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Failed to read input directory: %v\n", err)
-			os.Exit(1)
-		}
-
-		for _, file := range files {
-			if file.IsDir() || len(file.Name()) < 4 || file.Name()[len(file.Name())-4:] != ".pdf" {
-				continue
-			}
-			pdfPath := convertInputDir + string(os.PathSeparator) + file.Name()
-			outputPath := convertOutputDir + string(os.PathSeparator) + file.Name()[:len(file.Name())-4] + ".txt"
-
-			fmt.Printf("Processing %s...\n", file.Name())
-			extractedText, err := ocrService.ExtractTextFromPDF(pdfPath)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Textract failed for %s: %v\n", file.Name(), err)
-				continue
-			}
-
-			err = os.WriteFile(outputPath, []byte(extractedText), 0644)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Failed to write output for %s: %v\n", file.Name(), err)
-			}
+			return setupError(cmd, fmt.Errorf("failed to convert PDFs: %w", err))
 		}
 		fmt.Println("Processing complete.")
+		return nil
 	},
 }
 
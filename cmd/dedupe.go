@@ -0,0 +1,256 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/nicoalimin/resume-analyzer/interfaces"
+	"github.com/nicoalimin/resume-analyzer/pipeline"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var dedupeThreshold float64
+var dedupeMerge bool
+
+// duplicateCandidate holds the values of a stored candidate that are compared for duplicates
+type duplicateCandidate struct {
+	stored  interfaces.StoredCandidate
+	name    string
+	emails  map[string]bool
+	phones  map[string]bool
+	shingle map[string]bool
+}
+
+// duplicatePair is a pair of candidates that likely belong to the same person
+type duplicatePair struct {
+	a, b    *duplicateCandidate
+	score   float64
+	reasons []string
+}
+
+// dedupeCmd represents the dedupe command
+var dedupeCmd = &cobra.Command{
+	Use:   "dedupe",
+	Short: "Find and merge duplicate candidates in the candidate database",
+	Long: `Compares all candidates in the candidate database by their extracted name, email address
+and phone number, and by the similarity of the OCR text itself. A shared phone number only
+counts together with a similar name, since references and recruiters list theirs too.
+Likely duplicates are listed with their score. With --merge every listed pair is merged
+into a single candidate that keeps all document versions.
+
+All campaigns are compared unless --campaign is given.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if candidateStore == nil {
+			return errors.New("dedupe requires a database (set --db or database in the config file)")
+		}
+
+		stored, err := candidateStore.ListCandidates(viper.GetString("campaign"))
+		if err != nil {
+			return setupError(cmd, fmt.Errorf("failed to read candidates: %w", err))
+		}
+
+		candidates := make([]*duplicateCandidate, 0, len(stored))
+		for _, s := range stored {
+			candidates = append(candidates, newDuplicateCandidate(s))
+		}
+
+		pairs := findDuplicatePairs(candidates, dedupeThreshold)
+		if len(pairs) == 0 {
+			fmt.Printf("No duplicates found among %d candidates.\n", len(candidates))
+			return nil
+		}
+
+		fmt.Printf("Found %d likely duplicate pairs among %d candidates:\n\n", len(pairs), len(candidates))
+		for _, pair := range pairs {
+			fmt.Printf("%.2f  %s  <->  %s\n      %s\n",
+				pair.score, describeCandidate(pair.a), describeCandidate(pair.b), strings.Join(pair.reasons, ", "))
+		}
+
+		if !dedupeMerge {
+			fmt.Println("\nRun again with --merge to merge these pairs.")
+			return nil
+		}
+
+		// Pairs can chain (A-B, B-C), so every candidate is resolved to the record it was merged into
+		mergedInto := map[int64]int64{}
+		resolve := func(id int64) int64 {
+			for {
+				target, ok := mergedInto[id]
+				if !ok {
+					return id
+				}
+				id = target
+			}
+		}
+
+		fmt.Println()
+		for _, pair := range pairs {
+			target, source := mergeOrder(pair.a, pair.b)
+			targetID, sourceID := resolve(target.stored.ID), resolve(source.stored.ID)
+			if targetID == sourceID {
+				continue
+			}
+			if err := candidateStore.MergeCandidates(targetID, sourceID); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to merge %s into %s: %v\n", describeCandidate(source), describeCandidate(target), err)
+				continue
+			}
+			mergedInto[sourceID] = targetID
+			fmt.Printf("Merged %s into %s\n", describeCandidate(source), describeCandidate(target))
+		}
+		return nil
+	},
+}
+
+// newDuplicateCandidate collects the comparable values of a stored candidate
+func newDuplicateCandidate(stored interfaces.StoredCandidate) *duplicateCandidate {
+	candidate := &duplicateCandidate{
+		stored:  stored,
+		name:    stored.Name,
+		emails:  map[string]bool{},
+		phones:  map[string]bool{},
+		shingle: shingles(stored.Document.OCRText, 3),
+	}
+	if stored.Extraction != nil && pipeline.HasValue(stored.Extraction.Name) {
+		candidate.name = stored.Extraction.Name
+	}
+	// Only the candidate's own contact details count; the OCR text also holds those of
+	// references, former employers and recruiters
+	if stored.Extraction == nil {
+		return candidate
+	}
+	if email := pipeline.NormalizeEmail(stored.Extraction.Email); email != "" {
+		candidate.emails[email] = true
+	}
+	phone := stored.Extraction.Phone
+	if digits := phoneDigits(phone); len(digits) >= 8 && !pipeline.YearRangePattern.MatchString(strings.TrimSpace(phone)) {
+		candidate.phones[digits] = true
+	}
+	return candidate
+}
+
+// findDuplicatePairs scores every pair of candidates and returns those at or above the threshold,
+// best matches first
+func findDuplicatePairs(candidates []*duplicateCandidate, threshold float64) []duplicatePair {
+	var pairs []duplicatePair
+	for i := 0; i < len(candidates); i++ {
+		for j := i + 1; j < len(candidates); j++ {
+			pair := scoreDuplicatePair(candidates[i], candidates[j])
+			if pair.score >= threshold {
+				pairs = append(pairs, pair)
+			}
+		}
+	}
+	sort.SliceStable(pairs, func(i, j int) bool {
+		return pairs[i].score > pairs[j].score
+	})
+	return pairs
+}
+
+// scoreDuplicatePair combines the matching signals of two candidates into a score between 0 and 1.
+// A shared email address is almost always the same person. A shared phone number is weighed
+// with the name similarity, so it never reaches the default threshold on its own; otherwise the
+// name and text similarity are weighed equally.
+func scoreDuplicatePair(a, b *duplicateCandidate) duplicatePair {
+	pair := duplicatePair{a: a, b: b}
+	nameSimilarity := jaccard(tokenSet(a.name), tokenSet(b.name))
+	textSimilarity := jaccard(a.shingle, b.shingle)
+
+	if overlaps(a.emails, b.emails) {
+		pair.score = 1.0
+		pair.reasons = append(pair.reasons, "same email")
+	}
+	if overlaps(a.phones, b.phones) {
+		pair.score = max(pair.score, 0.5+0.4*nameSimilarity)
+		pair.reasons = append(pair.reasons, "same phone")
+	}
+
+	if nameSimilarity > 0 {
+		pair.reasons = append(pair.reasons, fmt.Sprintf("name %.0f%%", 100*nameSimilarity))
+	}
+	if textSimilarity > 0 {
+		pair.reasons = append(pair.reasons, fmt.Sprintf("text %.0f%%", 100*textSimilarity))
+	}
+	pair.score = max(pair.score, 0.5*nameSimilarity+0.5*textSimilarity)
+
+	return pair
+}
+
+// mergeOrder keeps the candidate with the most documents, or the older one on a tie
+func mergeOrder(a, b *duplicateCandidate) (target, source *duplicateCandidate) {
+	if b.stored.Documents > a.stored.Documents || (b.stored.Documents == a.stored.Documents && b.stored.ID < a.stored.ID) {
+		return b, a
+	}
+	return a, b
+}
+
+func describeCandidate(candidate *duplicateCandidate) string {
+	label := candidate.stored.Campaign + "/" + candidate.stored.Key
+	if candidate.name != "" {
+		label += " (" + candidate.name + ")"
+	}
+	return label
+}
+
+// phoneDigits strips everything but digits and keeps the last ten, which
+// makes numbers with and without country code compare equal
+func phoneDigits(phone string) string {
+	d := pipeline.OnlyDigits(phone)
+	if len(d) > 10 {
+		d = d[len(d)-10:]
+	}
+	return d
+}
+
+// shingles returns the set of n-word sequences in the text
+func shingles(text string, n int) map[string]bool {
+	tokens := pipeline.Tokenize(text)
+	set := map[string]bool{}
+	for i := 0; i+n <= len(tokens); i++ {
+		set[strings.Join(tokens[i:i+n], " ")] = true
+	}
+	return set
+}
+
+func tokenSet(text string) map[string]bool {
+	set := map[string]bool{}
+	for _, token := range pipeline.Tokenize(text) {
+		set[token] = true
+	}
+	return set
+}
+
+// jaccard returns the size of the intersection divided by the size of the union
+func jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	intersection := 0
+	for key := range a {
+		if b[key] {
+			intersection++
+		}
+	}
+	return float64(intersection) / float64(len(a)+len(b)-intersection)
+}
+
+func overlaps(a, b map[string]bool) bool {
+	for key := range a {
+		if b[key] {
+			return true
+		}
+	}
+	return false
+}
+
+func init() {
+	rootCmd.AddCommand(dedupeCmd)
+	dedupeCmd.Flags().Float64Var(&dedupeThreshold, "threshold", 0.75, "Minimum score (0-1) for a pair to be reported as a duplicate")
+	dedupeCmd.Flags().BoolVar(&dedupeMerge, "merge", false, "Merge every reported pair into a single candidate")
+}
@@ -0,0 +1,243 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nicoalimin/resume-analyzer/modules/auditlog"
+	"github.com/nicoalimin/resume-analyzer/modules/crypto/envelope"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var encryptionKeyOutput string
+
+// encryptionCmd represents the encryption command
+var encryptionCmd = &cobra.Command{
+	Use:   "encryption",
+	Short: "Manage encryption at rest of the files written by the pipeline",
+	Long: `With an encryption key configured (encryption_key, the ENCRYPTION_KEY environment variable,
+or encryption_key_file), every file the pipeline writes is encrypted with AES-256-GCM envelope
+encryption, and every command decrypts its input transparently. Unencrypted files from before
+encryption was enabled can still be read.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+var encryptionKeygenCmd = &cobra.Command{
+	Use:   "keygen",
+	Short: "Generate a new encryption key",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		key, err := envelope.GenerateKey()
+		if err != nil {
+			return setupError(cmd, err)
+		}
+		if encryptionKeyOutput == "" {
+			fmt.Println(key)
+			return nil
+		}
+		if _, err := os.Stat(encryptionKeyOutput); err == nil {
+			return setupError(cmd, fmt.Errorf("%s already exists; refusing to overwrite a key", encryptionKeyOutput))
+		}
+		if err := os.WriteFile(encryptionKeyOutput, []byte(key+"\n"), 0400); err != nil {
+			return setupError(cmd, fmt.Errorf("failed to write key: %w", err))
+		}
+		fmt.Printf("Key saved to %s\n", encryptionKeyOutput)
+		return nil
+	},
+}
+
+var encryptionEncryptCmd = &cobra.Command{
+	Use:   "encrypt <file or folder>...",
+	Short: "Encrypt existing pipeline files in place",
+	Long: `Encrypts every unencrypted pipeline file in the given files and folders (including
+subfolders) in place with the configured key. Only the kinds of files the pipeline writes are
+encrypted (.txt, .md, .json, .jsonl, .ndjson, .csv, .xlsx and .html); PDFs, databases, keys and
+the append-only audit and erasure logs are left alone.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := requireArtifactCipher()
+		if err != nil {
+			return setupError(cmd, err)
+		}
+		skip := unencryptedFiles()
+		count := rewriteArtifacts(args, func(path string, data []byte) ([]byte, bool, error) {
+			if envelope.IsEncrypted(data) || !artifactExtensions[strings.ToLower(filepath.Ext(path))] || skip[absPath(path)] {
+				return nil, false, nil
+			}
+			encrypted, err := c.Encrypt(data)
+			return encrypted, true, err
+		})
+		fmt.Printf("Encrypted %d files.\n", count)
+		return nil
+	},
+}
+
+var encryptionDecryptCmd = &cobra.Command{
+	Use:   "decrypt <file or folder>...",
+	Short: "Decrypt pipeline files in place",
+	Long: `Decrypts every encrypted file in the given files and folders (including subfolders) in place,
+e.g. before rotating the key or turning encryption off.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := requireArtifactCipher()
+		if err != nil {
+			return setupError(cmd, err)
+		}
+		count := rewriteArtifacts(args, func(path string, data []byte) ([]byte, bool, error) {
+			if !envelope.IsEncrypted(data) {
+				return nil, false, nil
+			}
+			plaintext, err := c.Decrypt(data)
+			return plaintext, true, err
+		})
+		fmt.Printf("Decrypted %d files.\n", count)
+		return nil
+	},
+}
+
+var encryptionCatCmd = &cobra.Command{
+	Use:   "cat <file>",
+	Short: "Print a pipeline file, decrypting it if needed",
+	Long: `Prints a pipeline file, decrypting it if needed. The encrypted prompts and responses in
+the audit log are decrypted as well.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		data, err := readArtifact(args[0])
+		if err == nil && absPath(args[0]) == absPath(viper.GetString("audit_log")) {
+			data, err = decryptAuditLog(data)
+		}
+		if err != nil {
+			return setupError(cmd, fmt.Errorf("failed to read %s: %w", args[0], err))
+		}
+		os.Stdout.Write(data)
+		return nil
+	},
+}
+
+// artifactExtensions are the kinds of files the pipeline writes, which encrypt rewrites
+var artifactExtensions = map[string]bool{
+	".txt": true, ".md": true, ".json": true, ".jsonl": true, ".ndjson": true,
+	".csv": true, ".xlsx": true, ".html": true,
+}
+
+// unencryptedFiles returns the files encrypt must leave alone whatever their extension: the
+// key file, and the audit and erasure logs, which are appended to line by line
+func unencryptedFiles() map[string]bool {
+	files := map[string]bool{absPath(erasureLogPath()): true}
+	for _, key := range []string{"audit_log", "encryption_key_file"} {
+		if path := viper.GetString(key); path != "" {
+			files[absPath(path)] = true
+		}
+	}
+	return files
+}
+
+// absPath returns the absolute form of path for comparisons, or path itself if it has none
+func absPath(path string) string {
+	if path == "" {
+		return ""
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	return abs
+}
+
+// decryptAuditLog decrypts the prompts and responses of every entry of an audit log
+func decryptAuditLog(data []byte) ([]byte, error) {
+	c, err := loadArtifactCipher()
+	if err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	for _, line := range bytes.Split(bytes.TrimRight(data, "\n"), []byte("\n")) {
+		var entry auditlog.Entry
+		if err := json.Unmarshal(line, &entry); err != nil || !entry.BodiesEncrypted {
+			out.Write(line)
+			out.WriteByte('\n')
+			continue
+		}
+		if err := auditlog.DecryptBodies(&entry, c); err != nil {
+			return nil, err
+		}
+		decrypted, err := json.Marshal(entry)
+		if err != nil {
+			return nil, err
+		}
+		out.Write(decrypted)
+		out.WriteByte('\n')
+	}
+	return out.Bytes(), nil
+}
+
+// requireArtifactCipher returns the configured cipher or an error if no key is configured
+func requireArtifactCipher() (*envelope.Cipher, error) {
+	c, err := loadArtifactCipher()
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption key: %w", err)
+	}
+	if c == nil {
+		return nil, errors.New("no encryption key configured (set encryption_key, ENCRYPTION_KEY or encryption_key_file)")
+	}
+	return c, nil
+}
+
+// rewriteArtifacts applies transform to every file under paths and writes back the files it changed.
+// It returns the number of rewritten files.
+func rewriteArtifacts(paths []string, transform func(path string, data []byte) ([]byte, bool, error)) int {
+	count := 0
+	for _, root := range paths {
+		filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to read %s: %v\n", path, err)
+				return nil
+			}
+			if entry.IsDir() {
+				return nil
+			}
+			info, err := entry.Info()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to read %s: %v\n", path, err)
+				return nil
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to read %s: %v\n", path, err)
+				return nil
+			}
+			result, changed, err := transform(path, data)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to process %s: %v\n", path, err)
+				return nil
+			}
+			if !changed {
+				return nil
+			}
+			if err := os.WriteFile(path, result, info.Mode().Perm()); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to write %s: %v\n", path, err)
+				return nil
+			}
+			count++
+			return nil
+		})
+	}
+	return count
+}
+
+func init() {
+	rootCmd.AddCommand(encryptionCmd)
+	encryptionCmd.AddCommand(encryptionKeygenCmd, encryptionEncryptCmd, encryptionDecryptCmd, encryptionCatCmd)
+	encryptionKeygenCmd.Flags().StringVarP(&encryptionKeyOutput, "output", "o", "", "Write the key to this file instead of printing it")
+}
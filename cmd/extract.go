@@ -0,0 +1,64 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/nicoalimin/resume-analyzer/interfaces"
+	"github.com/nicoalimin/resume-analyzer/modules/llm/bedrock"
+	"github.com/nicoalimin/resume-analyzer/pipeline"
+	"github.com/spf13/cobra"
+)
+
+var extractInputDir string
+var extractOutputDir string
+var extractLLMService interfaces.LLMService
+
+// extractCmd represents the extract command
+var extractCmd = &cobra.Command{
+	Use:   "extract",
+	Short: "Extract structured applicant data directly from the OCR text using AWS Bedrock",
+	Long: `Reads all .txt files produced by convert-pdfs, sends the raw text straight to AWS Bedrock
+together with the extraction schema and saves one JSON file per candidate to an output folder.
+The output folder can be passed to consolidate in place of a summaries folder.`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		// Initialize LLM service if not already set
+		if extractLLMService == nil {
+			extractLLMService = bedrock.NewBedrockService()
+		}
+		var err error
+		extractLLMService, err = wrapLLMService(extractLLMService)
+		return setupError(cmd, err)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if extractInputDir == "" || extractOutputDir == "" {
+			return errors.New("both --input and --output folders must be specified")
+		}
+
+		_, err := pipeline.Extract(pipeline.ExtractOptions{
+			Env:       pipelineEnv("Extracting", "Extraction"),
+			LLM:       extractLLMService,
+			InputDir:  extractInputDir,
+			OutputDir: extractOutputDir,
+		})
+		if err != nil {
+			return setupError(cmd, fmt.Errorf("failed to extract: %w", err))
+		}
+		fmt.Println("Extraction complete.")
+		return nil
+	},
+}
+
+// SetExtractLLMService allows dependency injection of LLM service (useful for testing)
+func SetExtractLLMService(service interfaces.LLMService) {
+	extractLLMService = service
+}
+
+func init() {
+	rootCmd.AddCommand(extractCmd)
+	extractCmd.Flags().StringVarP(&extractInputDir, "input", "i", "", "Input folder containing .txt files")
+	extractCmd.Flags().StringVarP(&extractOutputDir, "output", "o", "", "Output folder for extracted JSON files")
+}
@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/nicoalimin/resume-analyzer/interfaces"
+	keysqlite "github.com/nicoalimin/resume-analyzer/modules/keys/sqlite"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const defaultKeyStore = ".resume-analyzer-keys.db"
+
+var teamPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+var keysCreateTeam string
+var keysCreateName string
+
+// keysCmd represents the keys command
+var keysCmd = &cobra.Command{
+	Use:   "keys",
+	Short: "Manage the API keys of serve",
+	Long: `With serve --auth, every API request needs an API key. Each key belongs to a team workspace:
+it only sees the jobs, candidates and query results of its team. Keys are stored as SHA-256
+hashes in api_keys (default .resume-analyzer-keys.db), so a key is only shown when it is created.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+var keysCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Create an API key for a team",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if !teamPattern.MatchString(keysCreateTeam) {
+			return errors.New("--team must start with a letter or digit and only contain letters, digits, '.', '_' and '-'")
+		}
+		keys, err := openKeyStore()
+		if err != nil {
+			return setupError(cmd, err)
+		}
+		defer keys.Close()
+
+		key, secret, err := keys.Create(keysCreateTeam, keysCreateName)
+		if err != nil {
+			return setupError(cmd, err)
+		}
+		fmt.Printf("Created key %s for team %s:\n\n  %s\n\nStore it now, it cannot be shown again.\n", key.ID, key.Team, secret)
+		return nil
+	},
+}
+
+var keysRevokeCmd = &cobra.Command{
+	Use:   "revoke <id>...",
+	Short: "Revoke API keys",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		keys, err := openKeyStore()
+		if err != nil {
+			return setupError(cmd, err)
+		}
+		defer keys.Close()
+
+		failed := false
+		for _, id := range args {
+			key, err := keys.Revoke(id)
+			if err != nil {
+				if errors.Is(err, interfaces.ErrKeyNotFound) {
+					err = fmt.Errorf("API key %s not found", id)
+				}
+				fmt.Fprintf(os.Stderr, "%v\n", err)
+				failed = true
+				continue
+			}
+			fmt.Printf("Revoked key %s of team %s\n", key.ID, key.Team)
+		}
+		if failed {
+			return setupError(cmd, errors.New("not every key could be revoked"))
+		}
+		return nil
+	},
+}
+
+var keysListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the API keys",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		keys, err := openKeyStore()
+		if err != nil {
+			return setupError(cmd, err)
+		}
+		defer keys.Close()
+
+		list, err := keys.List()
+		if err != nil {
+			return setupError(cmd, err)
+		}
+		if len(list) == 0 {
+			fmt.Println("No API keys.")
+			return nil
+		}
+		fmt.Printf("%-16s %-16s %-20s %-10s %-16s %-16s %s\n", "ID", "Team", "Name", "Prefix", "Created", "Last used", "Status")
+		for _, key := range list {
+			status := "active"
+			if key.Revoked != nil {
+				status = "revoked " + formatKeyTime(key.Revoked)
+			}
+			fmt.Printf("%-16s %-16s %-20s %-10s %-16s %-16s %s\n",
+				key.ID, key.Team, key.Name, key.Prefix, formatKeyTime(&key.Created), formatKeyTime(key.LastUsed), status)
+		}
+		return nil
+	},
+}
+
+// openKeyStore opens the configured API key database
+func openKeyStore() (interfaces.KeyStore, error) {
+	path := viper.GetString("api_keys")
+	if path == "" {
+		path = defaultKeyStore
+	}
+	keys, err := keysqlite.NewSQLiteKeyStore(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open API keys: %w", err)
+	}
+	return keys, nil
+}
+
+func formatKeyTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
+
+func init() {
+	rootCmd.AddCommand(keysCmd)
+	keysCmd.AddCommand(keysCreateCmd, keysRevokeCmd, keysListCmd)
+	keysCreateCmd.Flags().StringVar(&keysCreateTeam, "team", "", "Team workspace the key gives access to")
+	keysCreateCmd.Flags().StringVar(&keysCreateName, "name", "", "Description of the key, e.g. who uses it")
+	keysCreateCmd.MarkFlagRequired("team")
+}
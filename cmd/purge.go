@@ -0,0 +1,687 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nicoalimin/resume-analyzer/modules/llm/redact"
+	"github.com/nicoalimin/resume-analyzer/modules/replay"
+	"github.com/nicoalimin/resume-analyzer/pipeline"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/xuri/excelize/v2"
+)
+
+const defaultErasureLog = "erasure-log.jsonl"
+
+var purgeCandidates []string
+var purgeExpired bool
+var purgeDryRun bool
+var purgeDirs []string
+var purgeFixtures []string
+var purgeAllCampaigns bool
+
+// purgeTarget is everything known about one candidate that is being erased
+type purgeTarget struct {
+	key    string
+	keys   map[string]bool // file names of the candidate, including merged duplicates and blind IDs
+	names  map[string]bool // names and contact details used to find rows without a key and mentions in other files
+	reason string
+}
+
+// erasureRecord is one line of the erasure log. The candidate is only recorded as a hash
+// of its key, so the log itself holds no personal data.
+type erasureRecord struct {
+	Time            time.Time `json:"time"`
+	Reason          string    `json:"reason"`
+	CandidateSHA256 string    `json:"candidate_sha256"`
+	FilesRemoved    int       `json:"files_removed"`
+	RowsRemoved     int       `json:"rows_removed"`
+	DatabaseRecords int       `json:"database_records"`
+	MappingEntries  int       `json:"mapping_entries"`
+	CacheEntries    int       `json:"cache_entries"`
+	AuditEntries    int       `json:"audit_entries"`
+	FixtureFiles    int       `json:"fixture_files"`
+	NeedsReview     []string  `json:"needs_review,omitempty"`
+}
+
+// purgeCmd represents the purge command
+var purgeCmd = &cobra.Command{
+	Use:   "purge",
+	Short: "Erase candidates from every pipeline artifact and the candidate database",
+	Long: `Removes candidates on request (--candidate, the file name without extension) or every candidate
+whose data is older than the retention policy (--expired, retention_months in the config file).
+
+For each candidate the PDF, OCR text, hyperlinks, summaries and extracted JSON are deleted from the
+pipeline folders, their rows are removed from consolidated CSV, JSON, JSONL and Excel files, and the
+candidate is deleted from the database, the redaction and blind mappings and the response cache.
+A duplicate that was merged into another candidate is erased from it without the other's records.
+Table rows are matched on the CV link or source file; the name is only used for rows without either.
+Database records are only erased in the current campaign (--campaign) unless --all-campaigns is set.
+The prompts and responses of the candidate's audit log entries are removed and the candidate's
+key in them is replaced by a hash. Replay fixtures in the --fixtures folders that hold the
+candidate's data are deleted. Other files that still mention the candidate, such as HTML reports and query responses, are listed for review.
+
+Every erasure is appended to the erasure log (erasure_log in the config file).`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(purgeCandidates) == 0 && !purgeExpired {
+			return errors.New("specify candidates with --candidate or use --expired")
+		}
+
+		var targets []*purgeTarget
+		for _, key := range purgeCandidates {
+			targets = append(targets, newPurgeTarget(key, "request"))
+		}
+		if purgeExpired {
+			months := viper.GetInt("retention_months")
+			if months <= 0 {
+				return errors.New("--expired requires retention_months in the config file or --retention-months")
+			}
+			expired, err := expiredCandidates(purgeDirs, time.Now().AddDate(0, -months, 0))
+			if err != nil {
+				return setupError(cmd, fmt.Errorf("failed to find expired candidates: %w", err))
+			}
+			for _, key := range expired {
+				targets = append(targets, newPurgeTarget(key, "retention"))
+			}
+		}
+
+		if len(targets) == 0 {
+			fmt.Println("No candidates to purge.")
+			return nil
+		}
+
+		for _, target := range targets {
+			if purgeDryRun {
+				fmt.Printf("Would purge %s (%s)\n", target.key, target.reason)
+				continue
+			}
+			record := purgeCandidate(target)
+			if err := appendErasureLog(record); err != nil {
+				return setupError(cmd, fmt.Errorf("failed to write erasure log: %w", err))
+			}
+			fmt.Printf("Purged %s (%s): %d files, %d table rows, %d database records, %d audit log entries, %d fixtures\n",
+				target.key, target.reason, record.FilesRemoved, record.RowsRemoved, record.DatabaseRecords,
+				record.AuditEntries, record.FixtureFiles)
+			for _, path := range record.NeedsReview {
+				fmt.Fprintf(os.Stderr, "Still mentions %s, regenerate or delete: %s\n", target.key, path)
+			}
+		}
+		return nil
+	},
+}
+
+// newPurgeTarget collects the names and contact details of a candidate from the extracted
+// JSON, the OCR text and the database before anything is deleted
+func newPurgeTarget(key string, reason string) *purgeTarget {
+	target := &purgeTarget{key: key, keys: map[string]bool{key: true}, names: map[string]bool{}, reason: reason}
+
+	for _, path := range findArtifacts(purgeDirs, target.keys) {
+		content, err := readArtifact(path)
+		if err != nil {
+			continue
+		}
+		switch {
+		case strings.HasSuffix(path, ".links.json"):
+		case strings.HasSuffix(path, ".json"):
+			var applicant ApplicantInfo
+			if json.Unmarshal(content, &applicant) == nil {
+				target.addIdentity(applicant)
+			}
+		case strings.HasSuffix(path, ".txt") && !strings.HasSuffix(path, "_summary.txt"):
+			target.addName(redact.ApplicantName(string(content)))
+		}
+	}
+
+	if candidateStore != nil {
+		stored, err := candidateStore.ListCandidates(purgeCampaign())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to read candidates: %v\n", err)
+		}
+		for _, candidate := range stored {
+			if candidate.Key != key {
+				continue
+			}
+			target.addName(candidate.Name)
+			if candidate.Extraction != nil {
+				target.addIdentity(*candidate.Extraction)
+			}
+		}
+	}
+	return target
+}
+
+func (t *purgeTarget) addName(name string) {
+	if pipeline.HasValue(name) {
+		t.names[strings.ToLower(strings.TrimSpace(name))] = true
+	}
+}
+
+func (t *purgeTarget) addIdentity(applicant ApplicantInfo) {
+	for _, value := range []string{applicant.Name, applicant.Email, applicant.Phone} {
+		t.addName(value)
+	}
+}
+
+// matches reports whether a table row belongs to the candidate. Rows are matched on their key,
+// the CV link or source file; the name is only used for rows that have neither, since two
+// candidates can share a name.
+func (t *purgeTarget) matches(key string, name string) bool {
+	if pipeline.HasValue(key) {
+		return t.keys[rowKey(key)]
+	}
+	name = strings.TrimSpace(name)
+	return t.keys[pipeline.CandidateBaseName(name)] || t.names[strings.ToLower(name)]
+}
+
+// rowKey returns the candidate key of a CV link, which is a local path, a file:// URL or the
+// configured base URL joined with the escaped PDF file name, or of a source file
+func rowKey(value string) string {
+	value = strings.TrimSpace(value)
+	if i := strings.LastIndex(value, "/"); i >= 0 && strings.Contains(value, "://") {
+		if name, err := url.PathUnescape(value[i+1:]); err == nil {
+			value = name
+		}
+	}
+	return pipeline.CandidateBaseName(value)
+}
+
+// purgeCampaign returns the campaign whose database records are purged, or an empty string
+// for every campaign with --all-campaigns
+func purgeCampaign() string {
+	if purgeAllCampaigns {
+		return ""
+	}
+	return currentCampaign()
+}
+
+// columnIndex returns the index of a column in a table header, or -1
+func columnIndex(header []string, name string) int {
+	for i, column := range header {
+		if strings.EqualFold(strings.TrimSpace(column), name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// cell returns a value of a table row, or an empty string if the row is too short
+func cell(row []string, index int) string {
+	if index < 0 || index >= len(row) {
+		return ""
+	}
+	return row[index]
+}
+
+// purgeCandidate erases one candidate everywhere and returns what was removed
+func purgeCandidate(target *purgeTarget) erasureRecord {
+	hash := sha256.Sum256([]byte(target.key))
+	record := erasureRecord{
+		Time:            time.Now().UTC(),
+		Reason:          target.reason,
+		CandidateSHA256: hex.EncodeToString(hash[:]),
+	}
+
+	// The key is either a stored candidate, whose merged duplicates have files of their own and
+	// are erased with it, or a duplicate that was merged into another candidate, of which only
+	// the rows stored under the key are erased
+	if candidateStore != nil {
+		matches, err := candidateStore.FindCandidates(purgeCampaign(), target.key)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to read candidates: %v\n", err)
+		}
+		for _, match := range matches {
+			if match.Alias {
+				if err := candidateStore.DeleteAlias(match.Ref); err != nil {
+					fmt.Fprintf(os.Stderr, "Failed to delete %s from the database: %v\n", target.key, err)
+					continue
+				}
+				record.DatabaseRecords++
+				continue
+			}
+			refs, err := candidateStore.DeleteCandidate(match.ID)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to delete %s from the database: %v\n", target.key, err)
+				continue
+			}
+			record.DatabaseRecords++
+			for _, ref := range refs {
+				target.keys[ref.Key] = true
+			}
+		}
+	}
+
+	record.MappingEntries += forgetBlindMapping(target)
+	record.MappingEntries += forgetRedactionMapping(target)
+	record.CacheEntries = forgetCachedResponses(target)
+	record.AuditEntries = forgetAuditLog(target)
+	record.FixtureFiles = forgetFixtures(target)
+
+	for _, path := range findArtifacts(purgeDirs, target.keys) {
+		if err := os.Remove(path); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to remove %s: %v\n", path, err)
+			continue
+		}
+		record.FilesRemoved++
+	}
+
+	for _, dir := range purgeDirs {
+		filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil || entry.IsDir() {
+				return nil
+			}
+			rows, mentioned, err := purgeRows(path, target)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to remove rows from %s: %v\n", path, err)
+			}
+			record.RowsRemoved += rows
+			if mentioned {
+				record.NeedsReview = append(record.NeedsReview, path)
+			}
+			return nil
+		})
+	}
+	return record
+}
+
+// findArtifacts returns every file in dirs that belongs to one of the keys
+func findArtifacts(dirs []string, keys map[string]bool) []string {
+	var paths []string
+	for _, dir := range dirs {
+		filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+			if err == nil && !entry.IsDir() && keys[artifactKey(entry.Name())] {
+				paths = append(paths, path)
+			}
+			return nil
+		})
+	}
+	return paths
+}
+
+// artifactKey returns the candidate key a pipeline file is named after
+func artifactKey(name string) string {
+	if strings.HasSuffix(name, ".links.json") {
+		return strings.TrimSuffix(name, ".links.json")
+	}
+	return pipeline.CandidateBaseName(name)
+}
+
+// purgeRows removes the candidate's rows from a consolidated output file. For files it cannot
+// rewrite it reports whether the candidate is still mentioned.
+func purgeRows(path string, target *purgeTarget) (int, bool, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return purgeCSVRows(path, target)
+	case ".json", ".jsonl", ".ndjson":
+		return purgeJSONRows(path, target)
+	case ".xlsx":
+		return purgeXLSXRows(path, target)
+	case ".pdf":
+		return 0, false, nil
+	}
+
+	content, err := readArtifact(path)
+	if err != nil {
+		return 0, false, err
+	}
+	lower := strings.ToLower(string(content))
+	for name := range target.names {
+		if strings.Contains(lower, name) {
+			return 0, true, nil
+		}
+	}
+	return 0, false, nil
+}
+
+func purgeCSVRows(path string, target *purgeTarget) (int, bool, error) {
+	content, err := readArtifact(path)
+	if err != nil {
+		return 0, false, err
+	}
+	records, err := csv.NewReader(bytes.NewReader(content)).ReadAll()
+	if err != nil || len(records) == 0 {
+		return 0, false, err
+	}
+
+	link := columnIndex(records[0], "CV Link")
+	kept := records[:1]
+	for _, record := range records[1:] {
+		if len(record) > 0 && target.matches(cell(record, link), record[0]) {
+			continue
+		}
+		kept = append(kept, record)
+	}
+	removed := len(records) - len(kept)
+	if removed == 0 {
+		return 0, false, nil
+	}
+
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	if err := writer.WriteAll(kept); err != nil {
+		return 0, false, err
+	}
+	return removed, false, writeArtifact(path, buf.Bytes(), 0644)
+}
+
+func purgeJSONRows(path string, target *purgeTarget) (int, bool, error) {
+	content, err := readArtifact(path)
+	if err != nil {
+		return 0, false, err
+	}
+
+	matches := func(record pipeline.ApplicantRecord) bool {
+		key := record.SourceFile
+		if !pipeline.HasValue(key) {
+			key = record.CVLink
+		}
+		return target.matches(key, record.Name)
+	}
+
+	if strings.HasSuffix(path, ".json") {
+		var records []pipeline.ApplicantRecord
+		if json.Unmarshal(content, &records) != nil {
+			// Not a consolidated table, e.g. the extracted JSON of another candidate
+			return 0, false, nil
+		}
+		var kept []pipeline.ApplicantRecord
+		for _, record := range records {
+			if !matches(record) {
+				kept = append(kept, record)
+			}
+		}
+		removed := len(records) - len(kept)
+		if removed == 0 {
+			return 0, false, nil
+		}
+		if kept == nil {
+			kept = []pipeline.ApplicantRecord{}
+		}
+		data, err := json.MarshalIndent(kept, "", "  ")
+		if err != nil {
+			return 0, false, err
+		}
+		return removed, false, writeArtifact(path, data, 0644)
+	}
+
+	var kept []string
+	removed := 0
+	for _, line := range strings.Split(strings.TrimRight(string(content), "\n"), "\n") {
+		var record pipeline.ApplicantRecord
+		if json.Unmarshal([]byte(line), &record) == nil && matches(record) {
+			removed++
+			continue
+		}
+		kept = append(kept, line)
+	}
+	if removed == 0 {
+		return 0, false, nil
+	}
+	data := strings.Join(kept, "\n")
+	if data != "" {
+		data += "\n"
+	}
+	return removed, false, writeArtifact(path, []byte(data), 0644)
+}
+
+func purgeXLSXRows(path string, target *purgeTarget) (int, bool, error) {
+	content, err := readArtifact(path)
+	if err != nil {
+		return 0, false, err
+	}
+	f, err := excelize.OpenReader(bytes.NewReader(content))
+	if err != nil {
+		return 0, false, err
+	}
+	defer f.Close()
+
+	removed := 0
+	for _, sheet := range f.GetSheetList() {
+		rows, err := f.GetRows(sheet)
+		if err != nil {
+			return 0, false, err
+		}
+		if len(rows) == 0 {
+			continue
+		}
+		link := columnIndex(rows[0], "CV Link")
+		// Rows are removed from the bottom up so the remaining row numbers stay valid
+		for i := len(rows) - 1; i >= 1; i-- {
+			if len(rows[i]) > 0 && target.matches(cell(rows[i], link), rows[i][0]) {
+				if err := f.RemoveRow(sheet, i+1); err != nil {
+					return removed, false, err
+				}
+				removed++
+			}
+		}
+	}
+	if removed == 0 {
+		return 0, false, nil
+	}
+	buf, err := f.WriteToBuffer()
+	if err != nil {
+		return removed, false, err
+	}
+	return removed, false, writeArtifact(path, buf.Bytes(), 0644)
+}
+
+// forgetBlindMapping removes the candidate from the blind mapping and adds its anonymous
+// IDs to the keys, so blind summaries are found as well
+func forgetBlindMapping(target *purgeTarget) int {
+	path := blindMappingPath()
+	if _, err := os.Stat(path); err != nil {
+		return 0
+	}
+	mapping, err := loadBlindMapping()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load blind mapping: %v\n", err)
+		return 0
+	}
+
+	removed := 0
+	for id, candidate := range mapping.Candidates {
+		if target.keys[candidate.Key] {
+			target.keys[id] = true
+			delete(mapping.Candidates, id)
+			removed++
+		}
+	}
+	if removed > 0 {
+		if err := mapping.Save(); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to save blind mapping: %v\n", err)
+		}
+	}
+	return removed
+}
+
+// forgetRedactionMapping removes the candidate's full names and contact details from the
+// redaction mapping. Single name parts like "Jane" are shared with every other candidate of
+// that name, so their entries are kept.
+func forgetRedactionMapping(target *purgeTarget) int {
+	path := viper.GetString("redaction_mapping")
+	if path == "" {
+		path = defaultRedactionMapping
+	}
+	if _, err := os.Stat(path); err != nil {
+		return 0
+	}
+	r, err := redact.NewRedactor(artifactFiles{}, path, nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load redaction mapping: %v\n", err)
+		return 0
+	}
+
+	var values []string
+	for name := range target.names {
+		values = append(values, name)
+	}
+	removed := r.Forget(values...)
+	if removed > 0 {
+		if err := r.Save(); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to save redaction mapping: %v\n", err)
+		}
+	}
+	return removed
+}
+
+// forgetCachedResponses removes every cached LLM and OCR response that holds the candidate's data
+func forgetCachedResponses(target *purgeTarget) int {
+	var keys []string
+	for key := range target.keys {
+		keys = append(keys, key)
+	}
+	store, err := newCacheStore()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to remove cached responses: %v\n", err)
+		return 0
+	}
+	removed, err := store.Forget(keys...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to remove cached responses: %v\n", err)
+	}
+	return removed
+}
+
+// forgetAuditLog removes the candidate's data from the audit log, if one is configured
+func forgetAuditLog(target *purgeTarget) int {
+	logger, err := openAuditLog()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to remove %s from the audit log: %v\n", target.key, err)
+		return 0
+	}
+	if logger == nil {
+		return 0
+	}
+	var keys []string
+	for key := range target.keys {
+		keys = append(keys, key)
+	}
+	changed, err := logger.Forget(keys...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to remove %s from the audit log: %v\n", target.key, err)
+	}
+	return changed
+}
+
+// forgetFixtures removes every replay fixture that holds the candidate's data
+func forgetFixtures(target *purgeTarget) int {
+	var keys, mentions []string
+	for key := range target.keys {
+		keys = append(keys, key)
+	}
+	for name := range target.names {
+		mentions = append(mentions, name)
+	}
+
+	removed := 0
+	for _, dir := range purgeFixtures {
+		count, err := replay.Forget(dir, keys, mentions)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to remove fixtures from %s: %v\n", dir, err)
+		}
+		removed += count
+	}
+	return removed
+}
+
+// expiredCandidates returns the keys of all candidates collected before cutoff. The creation
+// time in the database is used where there is one, since encryption and purges rewrite the
+// other pipeline files; candidates that are only on disk fall back to the date of their PDF.
+func expiredCandidates(dirs []string, cutoff time.Time) ([]string, error) {
+	created := map[string]time.Time{}
+	if candidateStore != nil {
+		stored, err := candidateStore.ListCandidates(purgeCampaign())
+		if err != nil {
+			return nil, err
+		}
+		for _, candidate := range stored {
+			if at, ok := created[candidate.Key]; !ok || candidate.CreatedAt.After(at) {
+				created[candidate.Key] = candidate.CreatedAt
+			}
+		}
+	}
+
+	fromFiles := map[string]time.Time{}
+	for _, dir := range dirs {
+		filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil || entry.IsDir() {
+				return nil
+			}
+			// PDFs are never rewritten by the pipeline, encryption or purges
+			key := artifactKey(entry.Name())
+			if !strings.EqualFold(filepath.Ext(entry.Name()), ".pdf") {
+				return nil
+			}
+			if _, ok := created[key]; ok {
+				return nil
+			}
+			if info, err := entry.Info(); err == nil && info.ModTime().After(fromFiles[key]) {
+				fromFiles[key] = info.ModTime()
+			}
+			return nil
+		})
+	}
+	for key, at := range fromFiles {
+		created[key] = at
+	}
+
+	var expired []string
+	for key, at := range created {
+		if at.Before(cutoff) {
+			expired = append(expired, key)
+		}
+	}
+	sort.Strings(expired)
+	return expired, nil
+}
+
+// erasureLogPath returns the configured erasure log
+func erasureLogPath() string {
+	if path := viper.GetString("erasure_log"); path != "" {
+		return path
+	}
+	return defaultErasureLog
+}
+
+// appendErasureLog appends a record to the erasure log
+func appendErasureLog(record erasureRecord) error {
+	path := erasureLogPath()
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+func init() {
+	rootCmd.AddCommand(purgeCmd)
+	purgeCmd.Flags().StringSliceVarP(&purgeCandidates, "candidate", "c", nil, "Candidate to erase, as the PDF file name without extension (repeatable)")
+	purgeCmd.Flags().BoolVar(&purgeExpired, "expired", false, "Erase every candidate older than the retention policy")
+	purgeCmd.Flags().Int("retention-months", 0, "Retention period in months (overrides retention_months in the config file)")
+	viper.BindPFlag("retention_months", purgeCmd.Flags().Lookup("retention-months"))
+	purgeCmd.Flags().BoolVar(&purgeAllCampaigns, "all-campaigns", false, "Erase database records in every campaign instead of only the current one")
+	purgeCmd.Flags().BoolVar(&purgeDryRun, "dry-run", false, "List the candidates that would be erased without deleting anything")
+	purgeCmd.Flags().StringSliceVar(&purgeFixtures, "fixtures", nil, "Replay fixture folders (see --record) to erase from")
+	purgeCmd.Flags().StringSliceVar(&purgeDirs, "dirs", []string{"input_pdfs", "output_txts", "output_summaries", "output_extracted", "output_consolidated"}, "Pipeline folders to erase from, including subfolders")
+}
@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nicoalimin/resume-analyzer/interfaces"
+	"github.com/nicoalimin/resume-analyzer/modules/llm/redact"
+	"github.com/nicoalimin/resume-analyzer/modules/store/sqlite"
+	"github.com/spf13/viper"
+)
+
+// usePurgeConfig points every file purge touches at a temporary folder and returns it
+func usePurgeConfig(t *testing.T) string {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	settings := map[string]string{
+		"blind_mapping":     filepath.Join(dir, "blind-map.json"),
+		"redaction_mapping": filepath.Join(dir, "redaction-map.json"),
+		"cache_dir":         filepath.Join(dir, "cache"),
+		"erasure_log":       filepath.Join(dir, "erasure-log.jsonl"),
+		"campaign":          "default",
+	}
+	for key, value := range settings {
+		viper.Set(key, value)
+	}
+	previousDirs := purgeDirs
+	purgeDirs = nil
+	t.Cleanup(func() {
+		for key := range settings {
+			viper.Set(key, "")
+		}
+		purgeDirs = previousDirs
+	})
+	return dir
+}
+
+func TestPurgeMergedAlias(t *testing.T) {
+	dir := usePurgeConfig(t)
+	store, err := sqlite.NewSQLiteStore(filepath.Join(dir, "candidates.db"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	SetCandidateStore(store)
+	defer SetCandidateStore(nil)
+
+	jane := interfaces.CandidateRef{Campaign: "default", Key: "jane_doe"}
+	duplicate := interfaces.CandidateRef{Campaign: "default", Key: "jane_doe_2"}
+	for _, ref := range []interfaces.CandidateRef{jane, duplicate} {
+		doc := interfaces.Document{Path: ref.Key + ".pdf", SHA256: ref.Key, OCRText: "Jane Doe"}
+		if err := store.SaveDocument(0, ref, doc); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.SaveSummary(0, ref, "Summary of "+ref.Key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ids := map[string]int64{}
+	stored, err := store.ListCandidates("default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, candidate := range stored {
+		ids[candidate.Key] = candidate.ID
+	}
+	if err := store.MergeCandidates(ids["jane_doe"], ids["jane_doe_2"]); err != nil {
+		t.Fatal(err)
+	}
+
+	record := purgeCandidate(newPurgeTarget("jane_doe_2", "request"))
+	if record.DatabaseRecords != 1 {
+		t.Errorf("erased %d database records, want 1", record.DatabaseRecords)
+	}
+
+	// The merge target keeps its own rows only
+	stored, err = store.ListCandidates("default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored) != 1 || stored[0].Key != "jane_doe" || stored[0].Documents != 1 ||
+		stored[0].Document.Path != "jane_doe.pdf" || stored[0].Summary != "Summary of jane_doe" {
+		t.Fatalf("candidates = %+v, want jane_doe with its own document and summary", stored)
+	}
+	matches, err := store.FindCandidates("default", "jane_doe_2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 0 {
+		t.Errorf("FindCandidates() = %+v, want the alias removed", matches)
+	}
+}
+
+func TestForgetRedactionMapping(t *testing.T) {
+	dir := usePurgeConfig(t)
+	path := filepath.Join(dir, "redaction-map.json")
+	r, err := redact.NewRedactor(artifactFiles{}, path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Redact("Jane Doe", "Jane Doe")
+	shared := r.Redact("Jane Roe, Jane", "Jane Roe")
+	if err := r.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	target := &purgeTarget{key: "jane_doe", keys: map[string]bool{"jane_doe": true}, names: map[string]bool{"jane doe": true}}
+	if removed := forgetRedactionMapping(target); removed != 1 {
+		t.Errorf("removed %d mapping entries, want only the full name", removed)
+	}
+
+	// The first name is shared with Jane Roe and keeps its token
+	r, err = redact.NewRedactor(artifactFiles{}, path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := r.Restore(shared); got != "Jane Roe, Jane" {
+		t.Errorf("Restore(%q) = %q, want Jane Roe's entries kept", shared, got)
+	}
+}
+
+func TestPurgeErrors(t *testing.T) {
+	usePurgeConfig(t)
+	rootCmd.SetOut(io.Discard)
+	rootCmd.SetErr(io.Discard)
+	defer rootCmd.SetOut(nil)
+	defer rootCmd.SetErr(nil)
+	defer purgeCmd.Flags().Set("expired", "false")
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"purge"}, "specify candidates"},
+		{[]string{"purge", "--expired"}, "requires retention_months"},
+	}
+	for _, test := range tests {
+		rootCmd.SetArgs(test.args)
+		if err := rootCmd.Execute(); err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%v: Execute() = %v, want an error containing %q", test.args, err, test.want)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/nicoalimin/resume-analyzer/modules/llm/redact"
+	"github.com/spf13/viper"
+)
+
+const defaultRedactionMapping = "redaction-map.json"
+
+var redactor *redact.Redactor
+
+// newRedactor creates the redactor with the built-in patterns plus any extra
+// patterns from redaction_patterns in the config file. The key of each extra pattern
+// is its category, so e.g. employee_id values are replaced by [EMPLOYEE_ID_1].
+func newRedactor() (*redact.Redactor, error) {
+	mappingPath := viper.GetString("redaction_mapping")
+	if mappingPath == "" {
+		mappingPath = defaultRedactionMapping
+	}
+
+	patterns := append([]redact.Pattern{}, redact.DefaultPatterns...)
+	for key, expr := range viper.GetStringMapString("redaction_patterns") {
+		category := strings.ToUpper(key)
+		if !redact.CategoryPattern.MatchString(category) {
+			return nil, fmt.Errorf("invalid redaction pattern %q: the name may only contain letters, digits and '_' and must start with a letter", key)
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %q: %w", key, err)
+		}
+		patterns = append(patterns, redact.Pattern{Category: category, Regexp: re})
+	}
+
+	return redact.NewRedactor(artifactFiles{}, mappingPath, patterns)
+}
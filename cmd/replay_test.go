@@ -0,0 +1,216 @@
+package cmd
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nicoalimin/resume-analyzer/interfaces"
+)
+
+// With -update the fixtures in testdata/fixtures are recorded again from scriptedLLM, e.g.
+// after a prompt changed: go test ./cmd -run TestReplay -update
+var update = flag.Bool("update", false, "record testdata/fixtures again from the scripted responses")
+
+const fixturesDir = "testdata/fixtures"
+
+const queryQuestion = "Who has worked with Kubernetes?"
+
+// scriptedLLM answers the prompts of the pipeline commands for the resumes in testdata/txts.
+// It is only used to record the fixtures; the tests replay them.
+type scriptedLLM struct{}
+
+func (s scriptedLLM) GenerateText(prompt string) (string, error) {
+	text, _, err := s.GenerateTextWithUsage(context.Background(), prompt)
+	return text, err
+}
+
+func (scriptedLLM) GenerateTextWithUsage(ctx context.Context, prompt string) (string, interfaces.TokenUsage, error) {
+	usage := interfaces.TokenUsage{InputTokens: len(prompt) / 4, OutputTokens: 120}
+	if strings.Contains(prompt, queryQuestion) {
+		return "Jane Doe moved the services of Acme to Kubernetes on AWS. John Roe has not worked with Kubernetes.", usage, nil
+	}
+
+	candidate := map[string]string{
+		"name": "Jane Doe", "role": "Backend Engineer", "seniority": "Senior", "status": "Active",
+		"current_position": "Senior Software Engineer", "current_company": "Acme Pte. Ltd.",
+		"years_of_exp": "10 years", "skillset": "Go, Python, Kubernetes, AWS, PostgreSQL, Terraform",
+		"remarks": "Led a team of five engineers", "email": "jane.doe@example.com", "phone": "+65 9123 4567",
+		"location": "Singapore", "linkedin": "linkedin.com/in/janedoe", "github": "github.com/janedoe",
+	}
+	if strings.Contains(prompt, "John Roe") {
+		candidate = map[string]string{
+			"name": "John Roe", "role": "Frontend Developer", "seniority": "Mid", "status": "Active",
+			"current_position": "Frontend Developer", "current_company": "Initech",
+			"years_of_exp": "4 years", "skillset": "TypeScript, React, Next.js, CSS",
+			"remarks": "Introduced end-to-end tests", "email": "john.roe@example.com", "phone": "+62 812 3456 7890",
+			"location": "Jakarta, Indonesia", "linkedin": "linkedin.com/in/johnroe", "github": "github.com/johnroe",
+		}
+	} else if !strings.Contains(prompt, "Jane Doe") {
+		return "", usage, fmt.Errorf("no scripted response for prompt %.60q", prompt)
+	}
+
+	switch {
+	case strings.Contains(prompt, "comprehensive summary"):
+		summary := fmt.Sprintf("**Name**: %s\n**Current Role/Position**: %s at %s\n**Years of Experience**: %s\n**Skills**: %s\n**Remarks**: %s\n",
+			candidate["name"], candidate["current_position"], candidate["current_company"],
+			candidate["years_of_exp"], candidate["skillset"], candidate["remarks"])
+		return summary, usage, nil
+	case strings.Contains(prompt, "from this resume summary"):
+		// Summaries have no contact details; those come from the OCR text
+		for _, field := range []string{"email", "phone", "location", "linkedin", "github"} {
+			delete(candidate, field)
+		}
+	}
+	candidate["cv_link"] = "N/A"
+	data, err := json.MarshalIndent(candidate, "", "  ")
+	return string(data), usage, err
+}
+
+// runCommand runs the CLI with args, replaying the fixtures or, with -update, recording them
+func runCommand(t *testing.T, args ...string) error {
+	t.Helper()
+	mode := []string{"--record=", "--replay=" + fixturesDir}
+	if *update {
+		mode = []string{"--record=" + fixturesDir, "--replay="}
+	}
+	rootCmd.SetArgs(append(mode, args...))
+	return rootCmd.Execute()
+}
+
+// readTable reads a consolidated CSV file keyed by applicant name
+func readTable(t *testing.T, path string) map[string]map[string]string {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := map[string]map[string]string{}
+	for _, record := range records[1:] {
+		row := map[string]string{}
+		for i, column := range records[0] {
+			row[column] = record[i]
+		}
+		rows[row["Applicant"]] = row
+	}
+	return rows
+}
+
+// TestReplay runs the pipeline commands offline against the recorded fixtures
+func TestReplay(t *testing.T) {
+	// The config file in the home folder must not change the results
+	t.Setenv("HOME", t.TempDir())
+	out := t.TempDir()
+
+	if *update {
+		if err := os.RemoveAll(fixturesDir); err != nil {
+			t.Fatal(err)
+		}
+		SetLLMService(scriptedLLM{})
+		SetExtractLLMService(scriptedLLM{})
+		SetConsolidateLLMService(scriptedLLM{})
+		SetQueryLLMService(scriptedLLM{})
+	}
+
+	// The commands write into existing folders
+	summaries, extracted := filepath.Join(out, "summaries"), filepath.Join(out, "extracted")
+	for _, dir := range []string{summaries, extracted} {
+		if err := os.Mkdir(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("summarize", func(t *testing.T) {
+		if err := runCommand(t, "summarize", "-i", "testdata/txts", "-o", summaries); err != nil {
+			t.Fatal(err)
+		}
+		for name, want := range map[string]string{
+			"jane_doe": "Senior Software Engineer at Acme Pte. Ltd.",
+			"john_roe": "Frontend Developer at Initech",
+		} {
+			summary, err := os.ReadFile(filepath.Join(summaries, name+"_summary.txt"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(summary), want) {
+				t.Errorf("summary of %s = %q, want it to contain %q", name, summary, want)
+			}
+		}
+	})
+
+	t.Run("extract", func(t *testing.T) {
+		if err := runCommand(t, "extract", "-i", "testdata/txts", "-o", extracted); err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(filepath.Join(extracted, "jane_doe.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var applicant ApplicantInfo
+		if err := json.Unmarshal(data, &applicant); err != nil {
+			t.Fatal(err)
+		}
+		// Contact details are normalized and the extracted values are verified
+		if applicant.Name != "Jane Doe" || applicant.Phone != "+6591234567" ||
+			applicant.LinkedIn != "https://www.linkedin.com/in/janedoe" || len(applicant.Unverified) != 0 {
+			t.Errorf("extracted = %+v", applicant)
+		}
+	})
+
+	t.Run("consolidate", func(t *testing.T) {
+		table := filepath.Join(out, "applicants.csv")
+		if err := runCommand(t, "consolidate", "-i", summaries, "-o", table, "-s", "testdata/txts"); err != nil {
+			t.Fatal(err)
+		}
+		rows := readTable(t, table)
+		if len(rows) != 2 {
+			t.Fatalf("got %d rows, want 2", len(rows))
+		}
+		jane, john := rows["Jane Doe"], rows["John Roe"]
+		if jane["Email"] != "jane.doe@example.com" || jane["Phone"] != "+6591234567" || jane["Unverified"] != "" {
+			t.Errorf("Jane Doe = %v", jane)
+		}
+		if john["Current Company"] != "Initech" || john["GitHub"] != "https://github.com/johnroe" {
+			t.Errorf("John Roe = %v", john)
+		}
+	})
+
+	t.Run("query", func(t *testing.T) {
+		answer := filepath.Join(out, "answer.txt")
+		if err := runCommand(t, "query", "-p", queryQuestion, "-i", "testdata/txts", "-o", answer); err != nil {
+			t.Fatal(err)
+		}
+		response, err := os.ReadFile(answer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(string(response), "Jane Doe moved the services of Acme to Kubernetes") {
+			t.Errorf("response = %q", response)
+		}
+	})
+}
+
+func TestRecordAndReplayConflict(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	rootCmd.SetErr(io.Discard)
+	defer rootCmd.SetErr(nil)
+
+	dir := t.TempDir()
+	rootCmd.SetArgs([]string{"--record", dir, "--replay", dir, "summarize", "-i", dir, "-o", dir})
+	err := rootCmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "cannot be used together") {
+		t.Errorf("Execute() = %v, want the --record and --replay conflict", err)
+	}
+}
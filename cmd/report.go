@@ -0,0 +1,325 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nicoalimin/resume-analyzer/pipeline"
+	"github.com/spf13/cobra"
+)
+
+//go:embed templates/report.html
+var reportTemplate string
+
+var reportDataPath string
+var reportSummariesDir string
+var reportOutputFile string
+var reportTitle string
+var reportFromDB bool
+
+// reportCandidate holds everything shown for a single candidate in the HTML report
+type reportCandidate struct {
+	ID      string
+	Record  pipeline.ApplicantRecord
+	Years   string
+	Summary string
+	// CVLink is the record's CV link if it is safe to link to
+	CVLink template.URL
+}
+
+// reportBar is a single labelled bar in one of the report charts
+type reportBar struct {
+	Label   string
+	Count   int
+	Percent float64
+}
+
+// reportData is the value passed to the HTML template
+type reportData struct {
+	Title       string
+	GeneratedAt string
+	Candidates  []reportCandidate
+	Seniority   []reportBar
+	Skills      []reportBar
+}
+
+// reportCmd represents the report command
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Generate a self-contained HTML report of a hiring batch",
+	Long: `Combines structured applicant data with the summaries into a single static HTML file
+containing a sortable candidate table, candidate cards, skill coverage and seniority charts.
+All styles and scripts are embedded so the file can be emailed and opened offline.
+
+The structured data is either a JSON or JSON Lines file written by consolidate, a folder
+of JSON files written by extract, or the candidate database with --from-db.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if (reportDataPath == "" && !reportFromDB) || reportOutputFile == "" {
+			return errors.New("both --data (or --from-db) and --output must be specified")
+		}
+
+		var records []pipeline.ApplicantRecord
+		var storedSummaries map[string]string
+		var err error
+		if reportFromDB {
+			records, storedSummaries, err = loadStoredRecords()
+		} else {
+			records, err = loadApplicantRecords(reportDataPath)
+		}
+		if err != nil {
+			return setupError(cmd, fmt.Errorf("failed to read applicant data: %w", err))
+		}
+		if len(records) == 0 {
+			return setupError(cmd, errors.New("no applicants found in the data"))
+		}
+
+		var candidates []reportCandidate
+		for i, record := range records {
+			candidate := reportCandidate{
+				ID:     fmt.Sprintf("candidate-%d", i+1),
+				Record: record,
+				CVLink: reportLink(record.CVLink),
+			}
+			if record.YearsOfExp != nil {
+				candidate.Years = fmt.Sprintf("%g", *record.YearsOfExp)
+			}
+
+			// Attach the full summary when the summaries folder is available
+			if summary, ok := storedSummaries[record.SourceFile]; ok {
+				candidate.Summary = summary
+			} else if reportSummariesDir != "" {
+				summaryPath := filepath.Join(reportSummariesDir, pipeline.CandidateBaseName(record.SourceFile)+"_summary.txt")
+				if content, err := readArtifact(summaryPath); err == nil {
+					candidate.Summary = string(content)
+				} else {
+					fmt.Fprintf(os.Stderr, "No summary found for %s: %v\n", record.Name, err)
+				}
+			}
+			candidates = append(candidates, candidate)
+		}
+
+		html, err := renderReport(reportData{
+			Title:       reportTitle,
+			GeneratedAt: time.Now().Format("2006-01-02 15:04"),
+			Candidates:  candidates,
+			Seniority:   seniorityDistribution(records),
+			Skills:      skillCoverage(records),
+		})
+		if err != nil {
+			return setupError(cmd, fmt.Errorf("failed to render report: %w", err))
+		}
+
+		err = writeArtifact(reportOutputFile, html, 0644)
+		if err != nil {
+			return setupError(cmd, fmt.Errorf("failed to write report: %w", err))
+		}
+
+		fmt.Printf("Report for %d candidates saved to %s\n", len(candidates), reportOutputFile)
+		return nil
+	},
+}
+
+// loadApplicantRecords reads a consolidate JSON/JSONL file or a folder of extract JSON files
+func loadApplicantRecords(path string) ([]pipeline.ApplicantRecord, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.IsDir() {
+		files, err := os.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		now := time.Now()
+		var records []pipeline.ApplicantRecord
+		for _, file := range files {
+			if file.IsDir() || !strings.HasSuffix(file.Name(), ".json") || strings.HasSuffix(file.Name(), ".links.json") {
+				continue
+			}
+			content, err := readArtifact(filepath.Join(path, file.Name()))
+			if err != nil {
+				return nil, err
+			}
+			var applicant ApplicantInfo
+			if err := json.Unmarshal(content, &applicant); err != nil {
+				return nil, fmt.Errorf("%s: %w", file.Name(), err)
+			}
+			applicant.SourceFile = file.Name()
+			records = append(records, pipeline.NewApplicantRecord(applicant, now))
+		}
+		return records, nil
+	}
+
+	content, err := readArtifact(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []pipeline.ApplicantRecord
+	if trimmed := bytes.TrimSpace(content); len(trimmed) > 0 && trimmed[0] == '[' {
+		err = json.Unmarshal(trimmed, &records)
+		return records, err
+	}
+
+	// Anything else is treated as JSON Lines
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var record pipeline.ApplicantRecord
+		if err := json.Unmarshal(line, &record); err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, scanner.Err()
+}
+
+// loadStoredRecords reads the latest extraction and summary of every candidate in the
+// current campaign from the candidate database. Summaries are keyed by candidate key,
+// which is also used as the record's source file.
+func loadStoredRecords() ([]pipeline.ApplicantRecord, map[string]string, error) {
+	if candidateStore == nil {
+		return nil, nil, fmt.Errorf("--from-db requires a database (set --db or database in the config file)")
+	}
+
+	candidates, err := candidateStore.ListCandidates(currentCampaign())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var records []pipeline.ApplicantRecord
+	summaries := map[string]string{}
+	for _, candidate := range candidates {
+		if candidate.Extraction == nil {
+			continue
+		}
+		applicant := *candidate.Extraction
+		applicant.SourceFile = candidate.Key
+		records = append(records, pipeline.NewApplicantRecord(applicant, candidate.UpdatedAt))
+		if candidate.Summary != "" {
+			summaries[candidate.Key] = candidate.Summary
+		}
+	}
+	return records, summaries, nil
+}
+
+// reportLink returns a CV link that can be used as a link target. html/template replaces
+// file:// URLs, which consolidate writes when no cv_base_url is set, with "#ZgotmplZ", so
+// links with a known scheme are passed through as trusted URLs and any other link is dropped.
+func reportLink(link string) template.URL {
+	u, err := url.Parse(strings.TrimSpace(link))
+	if err != nil || !pipeline.HasValue(link) {
+		return ""
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https", "file", "":
+		return template.URL(u.String())
+	}
+	return ""
+}
+
+// seniorityDistribution counts candidates per seniority level
+func seniorityDistribution(records []pipeline.ApplicantRecord) []reportBar {
+	counts := map[string]int{}
+	for _, record := range records {
+		level := strings.TrimSpace(record.Seniority)
+		if level == "" {
+			level = "Unknown"
+		}
+		counts[level]++
+	}
+	return toBars(counts, len(records), 0)
+}
+
+// skillCoverage counts how many candidates list each skill, case-insensitively
+func skillCoverage(records []pipeline.ApplicantRecord) []reportBar {
+	counts := map[string]int{}
+	labels := map[string]string{}
+	for _, record := range records {
+		seen := map[string]bool{}
+		for _, skill := range record.Skills {
+			key := strings.ToLower(skill)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			if _, ok := labels[key]; !ok {
+				labels[key] = skill
+			}
+			counts[key]++
+		}
+	}
+
+	labelled := make(map[string]int, len(counts))
+	for key, count := range counts {
+		labelled[labels[key]] = count
+	}
+	return toBars(labelled, len(records), 25)
+}
+
+// toBars sorts the counts in descending order and converts them to chart bars.
+// A limit of 0 keeps every entry.
+func toBars(counts map[string]int, total int, limit int) []reportBar {
+	bars := make([]reportBar, 0, len(counts))
+	for label, count := range counts {
+		bars = append(bars, reportBar{
+			Label:   label,
+			Count:   count,
+			Percent: 100 * float64(count) / float64(total),
+		})
+	}
+	sort.Slice(bars, func(i, j int) bool {
+		if bars[i].Count != bars[j].Count {
+			return bars[i].Count > bars[j].Count
+		}
+		return bars[i].Label < bars[j].Label
+	})
+	if limit > 0 && len(bars) > limit {
+		bars = bars[:limit]
+	}
+	return bars
+}
+
+// renderReport executes the embedded HTML template
+func renderReport(data reportData) ([]byte, error) {
+	tmpl, err := template.New("report").Funcs(template.FuncMap{
+		"join": strings.Join,
+	}).Parse(reportTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func init() {
+	rootCmd.AddCommand(reportCmd)
+	reportCmd.Flags().StringVarP(&reportDataPath, "data", "d", "", "Consolidated JSON/JSONL file or folder of extracted JSON files")
+	reportCmd.Flags().BoolVar(&reportFromDB, "from-db", false, "Read the campaign's candidates and summaries from the candidate database instead of --data")
+	reportCmd.Flags().StringVarP(&reportSummariesDir, "summaries", "s", "", "Folder containing summary files (optional)")
+	reportCmd.Flags().StringVarP(&reportOutputFile, "output", "o", "", "Output HTML file")
+	reportCmd.Flags().StringVarP(&reportTitle, "title", "t", "Hiring Batch Report", "Title shown at the top of the report")
+}
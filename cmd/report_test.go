@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nicoalimin/resume-analyzer/pipeline"
+)
+
+func TestRenderReportCVLinks(t *testing.T) {
+	links := map[string]string{
+		"file:///home/hr/input_pdfs/jane%20doe.pdf": `href="file:///home/hr/input_pdfs/jane%20doe.pdf"`,
+		"https://cvs.example.com/john_roe.pdf":      `href="https://cvs.example.com/john_roe.pdf"`,
+		"javascript:alert(document.cookie)":         "",
+		"N/A":                                       "",
+	}
+	var candidates []reportCandidate
+	for link := range links {
+		record := pipeline.ApplicantRecord{Name: "Jane Doe", CVLink: link}
+		candidates = append(candidates, reportCandidate{ID: "candidate-1", Record: record, CVLink: reportLink(link)})
+	}
+
+	html, err := renderReport(reportData{Title: "Batch", Candidates: candidates})
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := string(html)
+	if strings.Contains(report, "ZgotmplZ") {
+		t.Error("report contains a link that html/template rejected")
+	}
+	for link, href := range links {
+		if href != "" && !strings.Contains(report, href) {
+			t.Errorf("report does not link %s with %s", link, href)
+		}
+	}
+	if strings.Contains(report, `href="javascript:`) {
+		t.Error("report links a javascript: URL")
+	}
+}
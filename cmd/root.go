@@ -23,6 +23,8 @@ var rootCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		cmd.Help()
 	},
+	PersistentPreRunE: beforeCommand,
+	PersistentPostRun: afterCommand,
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
@@ -30,6 +32,8 @@ var rootCmd = &cobra.Command{
 func Execute() {
 	err := rootCmd.Execute()
 	if err != nil {
+		// PersistentPostRun only runs after commands that completed
+		closeCandidateStore("failed")
 		os.Exit(1)
 	}
 }
@@ -42,6 +46,24 @@ func init() {
 	// will be global for your application.
 
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.resume-analyzer.yaml)")
+	rootCmd.PersistentFlags().String("db", "", "SQLite candidate database to record results in (optional)")
+	rootCmd.PersistentFlags().String("campaign", "", "Campaign that candidates are stored under in the database (default \"default\")")
+	rootCmd.PersistentFlags().Bool("redact", false, "Replace personal data with placeholder tokens before sending text to the LLM")
+	rootCmd.PersistentFlags().String("audit-log", "", "Append every LLM and OCR call to this JSONL audit log (optional)")
+	rootCmd.PersistentFlags().Bool("no-cache", false, "Do not answer LLM and OCR calls from the response cache")
+	rootCmd.PersistentFlags().String("record", "", "Save every LLM and OCR response as a fixture in this folder")
+	rootCmd.PersistentFlags().String("replay", "", "Answer LLM and OCR calls from fixtures in this folder instead of AWS")
+	rootCmd.PersistentFlags().Float64("budget", 0, "Abort the run once the estimated LLM cost reaches this many USD (optional)")
+	rootCmd.PersistentFlags().Duration("budget-period", 0, "Start a new budget after this long, e.g. 24h for a daily budget of serve (default: never)")
+	viper.BindPFlag("database", rootCmd.PersistentFlags().Lookup("db"))
+	viper.BindPFlag("campaign", rootCmd.PersistentFlags().Lookup("campaign"))
+	viper.BindPFlag("redact", rootCmd.PersistentFlags().Lookup("redact"))
+	viper.BindPFlag("audit_log", rootCmd.PersistentFlags().Lookup("audit-log"))
+	viper.BindPFlag("no_cache", rootCmd.PersistentFlags().Lookup("no-cache"))
+	viper.BindPFlag("record", rootCmd.PersistentFlags().Lookup("record"))
+	viper.BindPFlag("replay", rootCmd.PersistentFlags().Lookup("replay"))
+	viper.BindPFlag("budget", rootCmd.PersistentFlags().Lookup("budget"))
+	viper.BindPFlag("budget_period", rootCmd.PersistentFlags().Lookup("budget-period"))
 
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
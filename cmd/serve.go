@@ -0,0 +1,217 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/nicoalimin/resume-analyzer/interfaces"
+	"github.com/nicoalimin/resume-analyzer/modules/llm/bedrock"
+	"github.com/nicoalimin/resume-analyzer/modules/ocr/textract"
+	queuesqlite "github.com/nicoalimin/resume-analyzer/modules/queue/sqlite"
+	"github.com/nicoalimin/resume-analyzer/modules/webhook"
+	"github.com/nicoalimin/resume-analyzer/server"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const defaultJobQueue = ".resume-analyzer-jobs.db"
+
+var serveAddr string
+var serveMaxUploadMB int64
+var serveMaxBatchFiles int
+var serveWorkers int
+var serveMaxAttempts int
+var serveContactFallback bool
+var serveNoUI bool
+var serveAuth bool
+var serveOCRService interfaces.OCRService
+var serveLLMService interfaces.LLMService
+
+// serveCmd represents the serve command
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve a REST API for analyzing and querying resumes",
+	Long: `Starts an HTTP server that analyzes uploaded PDF resumes with AWS Textract and Bedrock.
+Open the address in a browser for a web UI to upload resumes, follow their progress, browse
+and filter the candidates and ask questions; --no-ui serves the API only.
+
+Endpoints:
+  POST /api/analyze     analyze one PDF ("file" form field, or an application/pdf body) and
+                        return the OCR text, summary and structured fields
+  POST /api/jobs        queue the PDFs in the "files" form field as a batch job
+  GET  /api/jobs        list batch jobs
+  GET  /api/jobs/{id}   show the state and results of a batch job
+  POST /api/jobs/{id}/cancel
+                        cancel the files of a batch job that have not finished yet
+  GET  /api/candidates  list the profiles of every analyzed resume
+  POST /api/query       ask {"prompt": "...", "job_id": "..."} about the analyzed resumes
+  GET  /api/health      health check
+
+Errors are returned as {"error": {"code": "...", "message": "..."}}. Batch jobs are stored in
+a SQLite job queue (job_queue in the config file, default .resume-analyzer-jobs.db), so queued
+files are picked up again after a restart. Files that fail are retried up to --max-attempts times.
+The webhooks in the config file are notified when files and jobs finish.
+
+With --auth, every API request except the health check needs an API key created with
+"keys create", sent as "Authorization: Bearer <key>" or in the X-API-Key header. Each key only
+sees the jobs, candidates and query results of its team.`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		// Initialize services if not already set
+		if serveOCRService == nil {
+			serveOCRService = textract.NewTextractService()
+		}
+		var err error
+		if serveOCRService, err = wrapOCRService(serveOCRService); err != nil {
+			return setupError(cmd, err)
+		}
+		if serveLLMService == nil {
+			serveLLMService = bedrock.NewBedrockService()
+		}
+		if serveLLMService, err = wrapLLMService(serveLLMService); err != nil {
+			return setupError(cmd, err)
+		}
+		// Over budget, requests and batch files fail with usage.ErrBudgetExceeded instead of
+		// the whole server stopping
+		usageTracker.OnExceeded = nil
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		queue, err := openJobQueue()
+		if err != nil {
+			return setupError(cmd, fmt.Errorf("failed to open job queue: %w", err))
+		}
+		defer queue.Close()
+
+		// Files that were being analyzed when the server last stopped are analyzed again
+		requeued, err := queue.Requeue()
+		if err != nil {
+			return setupError(cmd, fmt.Errorf("failed to requeue interrupted files: %w", err))
+		}
+		if requeued > 0 {
+			fmt.Printf("Requeued %d interrupted files\n", requeued)
+		}
+
+		var keys interfaces.KeyStore
+		if serveAuth {
+			if keys, err = openKeyStore(); err != nil {
+				return setupError(cmd, err)
+			}
+			defer keys.Close()
+			active, err := hasActiveKey(keys)
+			if err != nil {
+				return setupError(cmd, err)
+			}
+			if !active {
+				return setupError(cmd, errors.New("no active API keys; create one with: resume-analyzer keys create --team <team>"))
+			}
+		}
+
+		dispatcher, err := openWebhooks()
+		if err != nil {
+			return setupError(cmd, err)
+		}
+		// Deliveries still being retried when the server stops get a little time to finish
+		defer dispatcher.Close(30 * time.Second)
+
+		api := server.New(server.Options{
+			OCR:              serveOCRService,
+			LLM:              serveLLMService,
+			PhoneCountryCode: viper.GetString("default_phone_country_code"),
+			ContactFallback:  serveContactFallback,
+			MaxUploadSize:    serveMaxUploadMB << 20,
+			MaxBatchFiles:    serveMaxBatchFiles,
+			Queue:            queue,
+			Workers:          serveWorkers,
+			MaxAttempts:      serveMaxAttempts,
+			Notifier:         notifier(dispatcher),
+			DisableUI:        serveNoUI,
+			Keys:             keys,
+		})
+		defer api.Close()
+
+		httpServer := &http.Server{
+			Addr:              serveAddr,
+			Handler:           api,
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		go func() {
+			<-ctx.Done()
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+			defer cancel()
+			httpServer.Shutdown(shutdownCtx)
+		}()
+
+		fmt.Printf("Listening on %s\n", serveAddr)
+		if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return setupError(cmd, fmt.Errorf("server failed: %w", err))
+		}
+		fmt.Println("Server stopped.")
+		return nil
+	},
+}
+
+// openJobQueue opens the configured job queue database
+func openJobQueue() (interfaces.JobQueue, error) {
+	path := viper.GetString("job_queue")
+	if path == "" {
+		path = defaultJobQueue
+	}
+	c, err := loadArtifactCipher()
+	if err != nil {
+		return nil, err
+	}
+	return queuesqlite.NewSQLiteQueue(path, c)
+}
+
+// hasActiveKey reports whether a key store has a key that has not been revoked
+func hasActiveKey(keys interfaces.KeyStore) (bool, error) {
+	list, err := keys.List()
+	if err != nil {
+		return false, err
+	}
+	for _, key := range list {
+		if key.Revoked == nil {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// notifier returns the dispatcher as a Notifier, or nil if no webhooks are configured
+func notifier(dispatcher *webhook.Dispatcher) interfaces.Notifier {
+	if dispatcher == nil {
+		return nil
+	}
+	return dispatcher
+}
+
+// SetServeOCRService allows dependency injection of the OCR service used by serve (useful for testing)
+func SetServeOCRService(service interfaces.OCRService) {
+	serveOCRService = service
+}
+
+// SetServeLLMService allows dependency injection of the LLM service used by serve (useful for testing)
+func SetServeLLMService(service interfaces.LLMService) {
+	serveLLMService = service
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+	serveCmd.Flags().StringVar(&serveAddr, "addr", ":8080", "Address to listen on")
+	serveCmd.Flags().Int64Var(&serveMaxUploadMB, "max-upload-mb", 10, "Maximum request body size in MB")
+	serveCmd.Flags().IntVar(&serveMaxBatchFiles, "max-batch-files", 50, "Maximum number of PDFs in one batch job")
+	serveCmd.Flags().IntVar(&serveWorkers, "workers", 2, "Number of batch files analyzed at the same time")
+	serveCmd.Flags().IntVar(&serveMaxAttempts, "max-attempts", 3, "Number of times a batch file is analyzed before it is marked as failed")
+	serveCmd.Flags().BoolVar(&serveContactFallback, "contact-fallback", false, "Ask the LLM for contact details that pattern matching could not find; only values that appear in the resume text are kept")
+	serveCmd.Flags().BoolVar(&serveNoUI, "no-ui", false, "Serve only the API, without the web UI")
+	serveCmd.Flags().BoolVar(&serveAuth, "auth", false, "Require an API key on every request and isolate the jobs of each team")
+}
@@ -0,0 +1,271 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/nicoalimin/resume-analyzer/interfaces"
+	"github.com/nicoalimin/resume-analyzer/modules/auditlog"
+	"github.com/nicoalimin/resume-analyzer/modules/cache"
+	"github.com/nicoalimin/resume-analyzer/modules/llm/bedrock"
+	"github.com/nicoalimin/resume-analyzer/modules/llm/redact"
+	"github.com/nicoalimin/resume-analyzer/modules/llm/usage"
+	"github.com/nicoalimin/resume-analyzer/modules/replay"
+	"github.com/nicoalimin/resume-analyzer/pipeline"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var currentCommand string
+var auditLogger *auditlog.Logger
+var usageTracker *usage.Tracker
+var responseCache *cache.Store
+
+// beforeCommand runs before every command
+func beforeCommand(cmd *cobra.Command, args []string) error {
+	currentCommand = cmd.Name()
+	return setupError(cmd, openCandidateStore(cmd, args))
+}
+
+// afterCommand runs after every command that completed
+func afterCommand(cmd *cobra.Command, args []string) {
+	printUsage()
+	printCacheHits()
+	closeCandidateStore("completed")
+}
+
+// setupError returns an error that stops a command. The usage is not printed, since the
+// flags were valid.
+func setupError(cmd *cobra.Command, err error) error {
+	if err != nil {
+		cmd.SilenceUsage = true
+	}
+	return err
+}
+
+// callContext returns the command and the candidates of an LLM call for the audit log,
+// usage report and cache
+func callContext(ctx context.Context) (string, []string) {
+	return currentCommand, interfaces.CallFrom(ctx).Candidates
+}
+
+// pipelineEnv returns the environment the pipeline stages run in for this command.
+// Progress is printed with verb for every file, and with saved for every output file
+// if it is not empty, e.g. "Summarizing a.txt..." and "Summary saved to a_summary.txt".
+func pipelineEnv(verb string, saved string) pipeline.Env {
+	return pipeline.Env{
+		Files:            artifactFiles{},
+		Store:            candidateStore,
+		RunID:            storeRunID,
+		Campaign:         currentCampaign(),
+		PhoneCountryCode: viper.GetString("default_phone_country_code"),
+		Progress: func(event pipeline.Event) {
+			switch event.Kind {
+			case pipeline.EventStarted:
+				fmt.Printf("%s %s...\n", verb, event.File)
+			case pipeline.EventSaved:
+				if saved != "" {
+					fmt.Printf("%s saved to %s\n", saved, event.Path)
+				}
+			case pipeline.EventFailed, pipeline.EventWarning:
+				fmt.Fprintf(os.Stderr, "%v\n", event.Err)
+			}
+		},
+	}
+}
+
+// wrapLLMService adds the optional behaviour configured for this run to an LLM service.
+// With --replay the service is replaced by recorded fixtures, and with --record the real
+// responses are saved as fixtures. With audit_log set, every call is appended to the audit
+// log. The token usage of every call is tracked so the cost of the run can be reported and
+// capped. Unless caching is turned off, repeated prompts are answered from the cache without
+// a call. With redaction enabled, personal data is replaced by placeholder tokens before any
+// prompt leaves the machine and restored in the response; the audit log records the redacted
+// prompt the model actually saw.
+func wrapLLMService(service interfaces.LLMService) (interfaces.LLMService, error) {
+	replayDir, err := replayFixtures()
+	if err != nil {
+		return nil, err
+	}
+	if replayDir != "" {
+		service = replay.NewLLMPlayer(replayDir)
+	} else if dir := viper.GetString("record"); dir != "" {
+		service = replay.NewLLMRecorder(service, dir)
+	}
+
+	logger, err := openAuditLog()
+	if err != nil {
+		return nil, err
+	}
+	if logger != nil {
+		service = auditlog.NewLLMService(service, logger, bedrock.ModelID(), callContext)
+	}
+	tracker, err := openUsageTracker()
+	if err != nil {
+		return nil, err
+	}
+	service = usage.NewLLMService(service, tracker, callContext)
+	store, err := openCache()
+	if err != nil {
+		return nil, err
+	}
+	if store != nil {
+		service = cache.NewLLMService(service, store, bedrock.ModelID(), bedrock.Parameters(), callContext)
+	}
+
+	if viper.GetBool("redact") {
+		if redactor == nil {
+			r, err := newRedactor()
+			if err != nil {
+				return nil, fmt.Errorf("failed to set up redaction: %w", err)
+			}
+			redactor = r
+		}
+		service = redact.NewRedactingService(service, redactor)
+	}
+	return service, nil
+}
+
+// wrapOCRService adds the optional behaviour configured for this run to an OCR service
+func wrapOCRService(service interfaces.OCRService) (interfaces.OCRService, error) {
+	replayDir, err := replayFixtures()
+	if err != nil {
+		return nil, err
+	}
+	if replayDir != "" {
+		service = replay.NewOCRPlayer(replayDir)
+	} else if dir := viper.GetString("record"); dir != "" {
+		service = replay.NewOCRRecorder(service, dir)
+	}
+
+	logger, err := openAuditLog()
+	if err != nil {
+		return nil, err
+	}
+	if logger != nil {
+		service = auditlog.NewOCRService(service, logger, "textract", callContext)
+	}
+	store, err := openCache()
+	if err != nil {
+		return nil, err
+	}
+	if store != nil {
+		service = cache.NewOCRService(service, store, "textract")
+	}
+	return service, nil
+}
+
+// openAuditLog returns the audit logger, or nil if no audit_log is configured. Logged
+// prompts and responses are encrypted when an encryption key is configured.
+func openAuditLog() (*auditlog.Logger, error) {
+	path := viper.GetString("audit_log")
+	if path == "" {
+		return nil, nil
+	}
+	if auditLogger == nil {
+		c, err := loadArtifactCipher()
+		if err != nil {
+			return nil, fmt.Errorf("invalid encryption key: %w", err)
+		}
+		auditLogger = auditlog.NewLogger(path, viper.GetBool("audit_log_bodies"), c)
+	}
+	return auditLogger, nil
+}
+
+// openUsageTracker returns the tracker for the token usage of this run, priced with the
+// built-in prices overridden by model_prices from the config file
+func openUsageTracker() (*usage.Tracker, error) {
+	if usageTracker != nil {
+		return usageTracker, nil
+	}
+
+	var configured []usage.Price
+	if err := viper.UnmarshalKey("model_prices", &configured); err != nil {
+		return nil, fmt.Errorf("invalid model_prices: %w", err)
+	}
+	prices := append(configured, usage.DefaultPrices...)
+
+	tracker, err := usage.NewTracker(bedrock.ModelID(), prices, viper.GetFloat64("budget"))
+	if err != nil {
+		return nil, err
+	}
+	tracker.Period = viper.GetDuration("budget_period")
+	var warnOnce sync.Once
+	tracker.OnNoUsage = func() {
+		warnOnce.Do(func() {
+			fmt.Fprintf(os.Stderr, "Warning: %s reported no token usage, so costs are underestimated and the budget may be exceeded\n", bedrock.ModelID())
+		})
+	}
+	tracker.OnExceeded = func(err error) {
+		fmt.Fprintf(os.Stderr, "Aborting: %v\n", err)
+		printUsage()
+		closeCandidateStore("aborted")
+		os.Exit(1)
+	}
+	usageTracker = tracker
+	return usageTracker, nil
+}
+
+// printUsage prints the token usage and estimated cost of the run, if any LLM calls were made
+func printUsage() {
+	if usageTracker == nil || usageTracker.Run().Calls == 0 {
+		return
+	}
+	fmt.Print("\n" + usageTracker.Summary())
+}
+
+// openCache returns the response cache, or nil if caching is turned off with --no-cache.
+// Cached responses are encrypted like every other pipeline file when a key is configured.
+// The cache is not used while recording or replaying fixtures, so every call reaches them.
+func openCache() (*cache.Store, error) {
+	if viper.GetBool("no_cache") || viper.GetString("record") != "" || viper.GetString("replay") != "" {
+		return nil, nil
+	}
+	if responseCache == nil {
+		store, err := newCacheStore()
+		if err != nil {
+			return nil, err
+		}
+		responseCache = store
+	}
+	return responseCache, nil
+}
+
+// newCacheStore opens the cache folder configured with cache_dir and cache_ttl
+func newCacheStore() (*cache.Store, error) {
+	c, err := loadArtifactCipher()
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption key: %w", err)
+	}
+	dir := viper.GetString("cache_dir")
+	if dir == "" {
+		dir = defaultCacheDir
+	}
+	ttl := defaultCacheTTL
+	if viper.IsSet("cache_ttl") {
+		ttl = viper.GetDuration("cache_ttl")
+	}
+	return cache.NewStore(dir, ttl, c), nil
+}
+
+// printCacheHits prints how many calls were answered from the cache, if any
+func printCacheHits() {
+	if responseCache == nil {
+		return
+	}
+	if hits, misses := responseCache.Hits(); hits > 0 {
+		fmt.Printf("Cache: %d of %d calls answered from the cache\n", hits, hits+misses)
+	}
+}
+
+// replayFixtures returns the fixture folder to replay calls from, or "" to make real calls
+func replayFixtures() (string, error) {
+	dir := viper.GetString("replay")
+	if dir != "" && viper.GetString("record") != "" {
+		return "", errors.New("--record and --replay cannot be used together")
+	}
+	return dir, nil
+}
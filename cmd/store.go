@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/nicoalimin/resume-analyzer/interfaces"
+	"github.com/nicoalimin/resume-analyzer/modules/store/sqlite"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var candidateStore interfaces.CandidateStore
+var storeRunID int64
+
+// SetCandidateStore allows dependency injection of the candidate store (useful for testing)
+func SetCandidateStore(store interfaces.CandidateStore) {
+	candidateStore = store
+}
+
+// openCandidateStore opens the configured database and records the start of the command.
+// Without a configured database every store helper is a no-op.
+func openCandidateStore(cmd *cobra.Command, args []string) error {
+	if candidateStore == nil {
+		path := viper.GetString("database")
+		if path == "" {
+			return nil
+		}
+		c, err := loadArtifactCipher()
+		if err != nil {
+			return fmt.Errorf("invalid encryption key: %w", err)
+		}
+		store, err := sqlite.NewSQLiteStore(path, c)
+		if err != nil {
+			return fmt.Errorf("failed to open candidate database: %w", err)
+		}
+		candidateStore = store
+	}
+
+	runID, err := candidateStore.StartRun(cmd.Name(), currentCampaign(), os.Args[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to record run: %v\n", err)
+		return nil
+	}
+	storeRunID = runID
+	return nil
+}
+
+// closeCandidateStore records the end of the command and closes the database
+func closeCandidateStore(status string) {
+	if candidateStore == nil {
+		return
+	}
+	if storeRunID != 0 {
+		if err := candidateStore.FinishRun(storeRunID, status); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to record run: %v\n", err)
+		}
+	}
+	candidateStore.Close()
+	candidateStore = nil
+}
+
+// currentCampaign returns the campaign that candidates are stored under
+func currentCampaign() string {
+	if campaign := viper.GetString("campaign"); campaign != "" {
+		return campaign
+	}
+	return "default"
+}
+
+// storeScore saves a named score to the candidate database, if one is configured
+func storeScore(key string, name string, value float64) {
+	if candidateStore == nil {
+		return
+	}
+	if err := candidateStore.SaveScore(storeRunID, candidateRef(key), name, value); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to store score %s for %s: %v\n", name, key, err)
+	}
+}
+
+func candidateRef(key string) interfaces.CandidateRef {
+	return interfaces.CandidateRef{Campaign: currentCampaign(), Key: key}
+}
@@ -4,19 +4,18 @@ Copyright © 2025 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
+	"errors"
 	"fmt"
-	"os"
-	"path/filepath"
-	"strings"
 
 	"github.com/nicoalimin/resume-analyzer/interfaces"
 	"github.com/nicoalimin/resume-analyzer/modules/llm/bedrock"
-	"github.com/nicoalimin/resume-analyzer/prompts"
+	"github.com/nicoalimin/resume-analyzer/pipeline"
 	"github.com/spf13/cobra"
 )
 
 var summarizeInputDir string
 var summarizeOutputDir string
+var summarizeBlind bool
 var llmService interfaces.LLMService
 
 // summarizeCmd represents the summarize command
@@ -25,58 +24,39 @@ var summarizeCmd = &cobra.Command{
 	Short: "Generate summaries of extracted text documents using AWS Bedrock",
 	Long: `Reads all .txt files from a folder, generates summaries using AWS Bedrock,
 and saves the summaries to an output folder.`,
-	PreRun: func(cmd *cobra.Command, args []string) {
+	PreRunE: func(cmd *cobra.Command, args []string) error {
 		// Initialize LLM service if not already set
 		if llmService == nil {
 			llmService = bedrock.NewBedrockService()
 		}
+		var err error
+		llmService, err = wrapLLMService(llmService)
+		return setupError(cmd, err)
 	},
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		if summarizeInputDir == "" || summarizeOutputDir == "" {
-			fmt.Fprintln(os.Stderr, "Both --input and --output folders must be specified.")
-			os.Exit(1)
+			return errors.New("both --input and --output folders must be specified")
 		}
 
-		files, err := os.ReadDir(summarizeInputDir)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Failed to read input directory: %v\n", err)
-			os.Exit(1)
+		opts := pipeline.SummarizeOptions{
+			Env:       pipelineEnv("Summarizing", "Summary"),
+			LLM:       llmService,
+			InputDir:  summarizeInputDir,
+			OutputDir: summarizeOutputDir,
 		}
-
-		for _, file := range files {
-			if file.IsDir() || !strings.HasSuffix(file.Name(), ".txt") {
-				continue
-			}
-
-			inputPath := filepath.Join(summarizeInputDir, file.Name())
-			outputPath := filepath.Join(summarizeOutputDir, strings.TrimSuffix(file.Name(), ".txt")+"_summary.txt")
-
-			fmt.Printf("Summarizing %s...\n", file.Name())
-
-			// Read the text file
-			content, err := os.ReadFile(inputPath)
+		if summarizeBlind {
+			blind, err := loadBlindMapping()
 			if err != nil {
-				fmt.Fprintf(os.Stderr, "Failed to read %s: %v\n", file.Name(), err)
-				continue
-			}
-
-			// Generate summary using LLM service
-			prompt := prompts.GetSummaryPrompt(string(content))
-			summary, err := llmService.GenerateText(prompt)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Bedrock failed for %s: %v\n", file.Name(), err)
-				continue
+				return setupError(cmd, fmt.Errorf("failed to load blind mapping: %w", err))
 			}
+			opts.Blind = blind
+		}
 
-			// Write the summary to output file
-			err = os.WriteFile(outputPath, []byte(summary), 0644)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Failed to write summary for %s: %v\n", file.Name(), err)
-			} else {
-				fmt.Printf("Summary saved to %s\n", outputPath)
-			}
+		if _, err := pipeline.Summarize(opts); err != nil {
+			return setupError(cmd, fmt.Errorf("failed to summarize: %w", err))
 		}
 		fmt.Println("Summarization complete.")
+		return nil
 	},
 }
 
@@ -99,4 +79,5 @@ func init() {
 	// summarizeCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 	summarizeCmd.Flags().StringVarP(&summarizeInputDir, "input", "i", "", "Input folder containing .txt files")
 	summarizeCmd.Flags().StringVarP(&summarizeOutputDir, "output", "o", "", "Output folder for summaries")
+	summarizeCmd.Flags().BoolVar(&summarizeBlind, "blind", false, "Write anonymized summaries named after anonymous candidate IDs for blind review")
 }
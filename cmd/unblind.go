@@ -0,0 +1,73 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nicoalimin/resume-analyzer/pipeline"
+	"github.com/spf13/cobra"
+)
+
+var unblindInputFile string
+var unblindOutputFile string
+
+// unblindCmd represents the unblind command
+var unblindCmd = &cobra.Command{
+	Use:   "unblind",
+	Short: "Replace anonymous candidate IDs in a blind review file with the candidates' names",
+	Long: `Reads a summary, CSV, JSON or HTML file written in blind mode and replaces every anonymous
+candidate ID with the candidate's name (or file name if no name is known), using the blind
+mapping file. Run this only once the hiring decision has been made.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if unblindInputFile == "" || unblindOutputFile == "" {
+			return errors.New("both --input and --output files must be specified")
+		}
+		if strings.EqualFold(filepath.Ext(unblindInputFile), ".xlsx") {
+			return errors.New("Excel workbooks cannot be unblinded; consolidate to CSV or JSON in blind mode instead")
+		}
+
+		mapping, err := loadBlindMapping()
+		if err != nil {
+			return setupError(cmd, fmt.Errorf("failed to load blind mapping: %w", err))
+		}
+
+		content, err := readArtifact(unblindInputFile)
+		if err != nil {
+			return setupError(cmd, fmt.Errorf("failed to read %s: %w", unblindInputFile, err))
+		}
+
+		unknown := map[string]bool{}
+		result := pipeline.BlindIDPattern.ReplaceAllStringFunc(string(content), func(id string) string {
+			candidate, ok := mapping.Candidates[id]
+			if !ok {
+				unknown[id] = true
+				return id
+			}
+			if candidate.Name != "" {
+				return candidate.Name
+			}
+			return candidate.Key
+		})
+		for id := range unknown {
+			fmt.Fprintf(os.Stderr, "Unknown candidate ID %s\n", id)
+		}
+
+		if err := writeArtifact(unblindOutputFile, []byte(result), 0644); err != nil {
+			return setupError(cmd, fmt.Errorf("failed to write %s: %w", unblindOutputFile, err))
+		}
+		fmt.Printf("De-anonymized file saved to %s\n", unblindOutputFile)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(unblindCmd)
+	unblindCmd.Flags().StringVarP(&unblindInputFile, "input", "i", "", "File written in blind mode")
+	unblindCmd.Flags().StringVarP(&unblindOutputFile, "output", "o", "", "Output file with names restored")
+}
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/nicoalimin/resume-analyzer/modules/webhook"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	defaultWebhookAttempts   = 5
+	defaultWebhookRetryDelay = 2 * time.Second
+)
+
+// webhooksCmd represents the webhooks command
+var webhooksCmd = &cobra.Command{
+	Use:   "webhooks",
+	Short: "Manage the webhooks notified about serve jobs",
+	Long: `Webhooks are configured under webhooks in the config file. serve posts a signed JSON event to
+every endpoint when a resume of a batch job has been analyzed or has failed, and when a job is
+finished or canceled.`,
+}
+
+var webhooksTestCmd = &cobra.Command{
+	Use:   "test",
+	Short: "Send a ping event to every configured webhook",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dispatcher, err := openWebhooks()
+		if err != nil {
+			return setupError(cmd, err)
+		}
+		if dispatcher == nil {
+			return setupError(cmd, errors.New("no webhooks configured"))
+		}
+
+		failed := false
+		for url, err := range dispatcher.SendNow(webhook.EventPing, map[string]string{"message": "resume-analyzer webhook test"}) {
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", url, err)
+				failed = true
+				continue
+			}
+			fmt.Printf("%s: ok\n", url)
+		}
+		if failed {
+			return setupError(cmd, errors.New("not every webhook could be reached"))
+		}
+		return nil
+	},
+}
+
+// openWebhooks returns a dispatcher for the webhooks in the config file, or nil if there are none
+func openWebhooks() (*webhook.Dispatcher, error) {
+	var endpoints []webhook.Endpoint
+	if err := viper.UnmarshalKey("webhooks", &endpoints); err != nil {
+		return nil, fmt.Errorf("invalid webhooks: %w", err)
+	}
+	if len(endpoints) == 0 {
+		return nil, nil
+	}
+
+	for i, endpoint := range endpoints {
+		if endpoint.URL == "" {
+			return nil, fmt.Errorf("webhook %d has no url", i+1)
+		}
+		if endpoint.Team != "" && !teamPattern.MatchString(endpoint.Team) {
+			return nil, fmt.Errorf("webhook %s: invalid team %q", endpoint.URL, endpoint.Team)
+		}
+		if endpoint.SecretEnv != "" {
+			endpoints[i].Secret = os.Getenv(endpoint.SecretEnv)
+			if endpoints[i].Secret == "" {
+				return nil, fmt.Errorf("webhook %s: environment variable %s is not set", endpoint.URL, endpoint.SecretEnv)
+			}
+		}
+	}
+
+	attempts := defaultWebhookAttempts
+	if viper.IsSet("webhook_attempts") {
+		attempts = viper.GetInt("webhook_attempts")
+	}
+	dispatcher := webhook.NewDispatcher(endpoints, attempts, defaultWebhookRetryDelay)
+	dispatcher.OnFailure = func(url string, event webhook.Event, err error) {
+		fmt.Fprintf(os.Stderr, "Webhook %s for %s failed: %v\n", event.Type, url, err)
+	}
+	return dispatcher, nil
+}
+
+func init() {
+	rootCmd.AddCommand(webhooksCmd)
+	webhooksCmd.AddCommand(webhooksTestCmd)
+}
@@ -1,5 +1,13 @@
 package interfaces
 
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"time"
+)
+
 // OCRService defines the interface for Optical Character Recognition services
 type OCRService interface {
 	// ExtractTextFromPDF extracts text from a PDF file
@@ -13,3 +21,289 @@ type LLMService interface {
 	// Returns the generated text and any error
 	GenerateText(prompt string) (string, error)
 }
+
+// FileStore reads and writes the files produced by the pipeline, e.g. to encrypt them at rest
+type FileStore interface {
+	// ReadFile returns the content of a file
+	ReadFile(path string) ([]byte, error)
+	// WriteFile replaces the content of a file, creating it with perm if it does not exist
+	WriteFile(path string, data []byte, perm os.FileMode) error
+}
+
+// TokenUsage holds the number of tokens processed by one LLM call
+type TokenUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+// UsageLLMService is implemented by LLM services that report the token usage of each call
+// and take a context, e.g. to cancel the call or to learn whose data it contains.
+// Wrappers around an LLMService implement it as well, so usage and context are passed through.
+type UsageLLMService interface {
+	LLMService
+	// GenerateTextWithUsage generates text like GenerateText and also returns the token usage
+	GenerateTextWithUsage(ctx context.Context, prompt string) (string, TokenUsage, error)
+}
+
+// GenerateTextWithUsage calls the service and returns the token usage if the service reports it
+func GenerateTextWithUsage(ctx context.Context, service LLMService, prompt string) (string, TokenUsage, error) {
+	if usageService, ok := service.(UsageLLMService); ok {
+		return usageService.GenerateTextWithUsage(ctx, prompt)
+	}
+	text, err := service.GenerateText(prompt)
+	return text, TokenUsage{}, err
+}
+
+// GenerateTextContext calls the service with ctx if it takes a context
+func GenerateTextContext(ctx context.Context, service LLMService, prompt string) (string, error) {
+	text, _, err := GenerateTextWithUsage(ctx, service, prompt)
+	return text, err
+}
+
+// Call describes whose data an LLM call contains
+type Call struct {
+	// Candidates are the keys of the candidates whose data is sent
+	Candidates []string
+	// Texts are the resume texts of those candidates, e.g. to learn their names for redaction
+	Texts []string
+	// Team is the team workspace the call is made for, if any, so cached responses are not
+	// shared between teams
+	Team string
+}
+
+type callKey struct{}
+
+// WithCall returns a context that carries the description of an LLM call
+func WithCall(ctx context.Context, call Call) context.Context {
+	return context.WithValue(ctx, callKey{}, call)
+}
+
+// CallFrom returns the description of the LLM call carried by ctx, if any
+func CallFrom(ctx context.Context) Call {
+	call, _ := ctx.Value(callKey{}).(Call)
+	return call
+}
+
+// ApplicantInfo holds the structured information extracted for one applicant
+type ApplicantInfo struct {
+	Name            string   `json:"name"`
+	Role            string   `json:"role"`
+	Seniority       string   `json:"seniority"`
+	Status          string   `json:"status"`
+	CurrentPosition string   `json:"current_position"`
+	CurrentCompany  string   `json:"current_company"`
+	YearsOfExp      string   `json:"years_of_exp"`
+	CVLink          string   `json:"cv_link"`
+	Skillset        string   `json:"skillset"`
+	Remarks         string   `json:"remarks"`
+	Email           string   `json:"email,omitempty"`
+	Phone           string   `json:"phone,omitempty"`
+	Location        string   `json:"location,omitempty"`
+	LinkedIn        string   `json:"linkedin,omitempty"`
+	GitHub          string   `json:"github,omitempty"`
+	Portfolio       string   `json:"portfolio,omitempty"`
+	Unverified      []string `json:"unverified,omitempty"`
+	SourceFile      string   `json:"-"`
+}
+
+// CandidateRef identifies a candidate within a campaign by the base name of their files
+type CandidateRef struct {
+	Campaign string
+	Key      string
+}
+
+// Document is a source document and the text and hyperlinks extracted from it
+type Document struct {
+	Path    string
+	SHA256  string
+	OCRText string
+	Links   []string
+}
+
+// StoredCandidate is a candidate together with the latest document and results of every pipeline stage
+type StoredCandidate struct {
+	ID         int64
+	Campaign   string
+	Key        string
+	Name       string
+	Documents  int
+	Document   Document
+	Summary    string
+	Extraction *ApplicantInfo
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// CandidateMatch is a stored candidate found by key, either directly or as a duplicate that
+// was merged into it
+type CandidateMatch struct {
+	// ID is the stored candidate, which is the merge target for aliases
+	ID int64
+	// Ref is the campaign and key that matched
+	Ref CandidateRef
+	// Alias is set if the key was merged into the candidate
+	Alias bool
+}
+
+// CandidateStore defines the interface for persisting candidates and pipeline results
+type CandidateStore interface {
+	// StartRun records the start of a pipeline command and returns the run ID
+	StartRun(command string, campaign string, args []string) (int64, error)
+	// FinishRun records the final status of a run
+	FinishRun(runID int64, status string) error
+	// SaveDocument stores a source document and its OCR text for a candidate. A document with
+	// the same SHA-256 as a stored one of the candidate replaces it.
+	SaveDocument(runID int64, candidate CandidateRef, doc Document) error
+	// SaveSummary stores a generated summary for a candidate
+	SaveSummary(runID int64, candidate CandidateRef, summary string) error
+	// SaveExtraction stores the extracted structured fields for a candidate
+	SaveExtraction(runID int64, candidate CandidateRef, applicant ApplicantInfo) error
+	// SaveScore stores a named numeric score for a candidate
+	SaveScore(runID int64, candidate CandidateRef, name string, value float64) error
+	// ListCandidates returns all candidates of a campaign, or of every campaign if it is empty
+	ListCandidates(campaign string) ([]StoredCandidate, error)
+	// MergeCandidates moves all documents and results of the source candidate to the target
+	// candidate and removes the source. Later writes for the source are stored on the target.
+	MergeCandidates(targetID int64, sourceID int64) error
+	// DeleteCandidate removes a candidate with all of its documents and results and returns
+	// the campaign and key of the candidate and of every candidate that was merged into it
+	DeleteCandidate(id int64) ([]CandidateRef, error)
+	// FindCandidates returns the candidates stored under a key in a campaign, or in every
+	// campaign if it is empty, including candidates the key was merged into
+	FindCandidates(campaign string, key string) ([]CandidateMatch, error)
+	// DeleteAlias removes a merged duplicate from the candidate it was merged into, together
+	// with the documents and results that were stored under its key
+	DeleteAlias(ref CandidateRef) error
+	// Close releases the underlying resources
+	Close() error
+}
+
+// ErrJobNotFound is returned by a JobQueue for an unknown job ID
+var ErrJobNotFound = errors.New("job not found")
+
+// JobState is the state of a job or of one file in it
+type JobState string
+
+const (
+	// JobQueued is waiting for a worker
+	JobQueued JobState = "queued"
+	// JobRunning is being analyzed
+	JobRunning JobState = "running"
+	// JobSucceeded has been analyzed
+	JobSucceeded JobState = "succeeded"
+	// JobFailed could not be analyzed after all attempts
+	JobFailed JobState = "failed"
+	// JobCanceled was canceled before it finished
+	JobCanceled JobState = "canceled"
+)
+
+// Job is a batch of files analyzed in the background. Its state is derived from its files:
+// running until every file is finished, then succeeded if at least one file succeeded.
+type Job struct {
+	ID       string     `json:"id"`
+	Team     string     `json:"team,omitempty"`
+	State    JobState   `json:"state"`
+	Created  time.Time  `json:"created_at"`
+	Finished *time.Time `json:"finished_at,omitempty"`
+	Files    []JobFile  `json:"files"`
+}
+
+// JobFile is one file of a job
+type JobFile struct {
+	TaskID   int64           `json:"task_id"`
+	Name     string          `json:"name"`
+	State    JobState        `json:"state"`
+	Attempts int             `json:"attempts"`
+	Error    string          `json:"error,omitempty"`
+	Result   json.RawMessage `json:"result,omitempty"`
+}
+
+// JobUpload is a file submitted as part of a job
+type JobUpload struct {
+	Name    string
+	Content []byte
+}
+
+// JobTask is a file of a job claimed by a worker
+type JobTask struct {
+	ID       int64
+	JobID    string
+	Team     string
+	Name     string
+	Content  []byte
+	Attempts int
+}
+
+// JobQueue defines the interface for a durable queue of analysis jobs. Each file of a job is
+// a task that a worker claims, and that is finished with Complete, Retry or Fail.
+//
+// Every job belongs to a team. Get, List and Cancel only see the jobs of the given team, and
+// return ErrJobNotFound for jobs of other teams; an empty team sees the jobs of every team.
+type JobQueue interface {
+	// Submit stores a new job of team with one queued task per file
+	Submit(team string, files []JobUpload) (Job, error)
+	// Get returns a job with the state and result of each file
+	Get(team string, id string) (Job, error)
+	// List returns all jobs, newest first
+	List(team string) ([]Job, error)
+	// Cancel marks the unfinished files of a job as canceled. Results of files that are
+	// running when the job is canceled are discarded.
+	Cancel(team string, id string) (Job, error)
+	// Claim marks the oldest queued task that is due as running, counts the attempt and
+	// returns it, or returns nil if there is none
+	Claim() (*JobTask, error)
+	// Complete stores the result of a running task
+	Complete(taskID int64, result []byte) error
+	// Retry queues a running task again once at has passed
+	Retry(taskID int64, reason string, at time.Time) error
+	// Fail marks a running task as failed
+	Fail(taskID int64, reason string) error
+	// ClaimFinished returns the job if all of its files are finished and it has not been
+	// returned by ClaimFinished before, so its completion is reported exactly once.
+	// Otherwise it returns nil.
+	ClaimFinished(id string) (*Job, error)
+	// Requeue queues the tasks that were left running by a process that stopped, and
+	// returns how many there were. It must only be called when no worker is running.
+	Requeue() (int, error)
+	// Close releases the underlying resources
+	Close() error
+}
+
+// ErrKeyNotFound is returned for an API key ID that does not exist
+var ErrKeyNotFound = errors.New("API key not found")
+
+// APIKey authenticates requests to the server for one team. Only a hash of the secret key
+// is stored.
+type APIKey struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Team string `json:"team"`
+	// Prefix is the start of the secret key, to recognize it
+	Prefix   string     `json:"prefix"`
+	Created  time.Time  `json:"created_at"`
+	LastUsed *time.Time `json:"last_used_at,omitempty"`
+	Revoked  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// KeyStore defines the interface for storing API keys
+type KeyStore interface {
+	// Create generates a key for team and returns it with the secret key, which cannot be
+	// retrieved later
+	Create(team string, name string) (APIKey, string, error)
+	// Authenticate returns the key with the given secret, or nil if there is none or it has
+	// been revoked
+	Authenticate(secret string) (*APIKey, error)
+	// Revoke marks a key as revoked, so it no longer authenticates requests
+	Revoke(id string) (APIKey, error)
+	// List returns all keys, including revoked ones, oldest first
+	List() ([]APIKey, error)
+	// Close releases the underlying resources
+	Close() error
+}
+
+// Notifier sends notifications about events, e.g. to webhooks
+type Notifier interface {
+	// Send delivers an event of the given type with data encoded as JSON, without waiting.
+	// team is the team workspace the event belongs to, or empty for events of no team.
+	Send(team string, eventType string, data any)
+}
@@ -0,0 +1,322 @@
+package auditlog
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nicoalimin/resume-analyzer/interfaces"
+	"github.com/nicoalimin/resume-analyzer/modules/crypto/envelope"
+)
+
+// Entry is one line of the audit log
+type Entry struct {
+	Time           time.Time `json:"time"`
+	Command        string    `json:"command"`
+	Candidates     []string  `json:"candidates"`
+	Service        string    `json:"service"`
+	Model          string    `json:"model"`
+	PromptSHA256   string    `json:"prompt_sha256,omitempty"`
+	DocumentSHA256 string    `json:"document_sha256,omitempty"`
+	InputTokens    int       `json:"input_tokens"`
+	OutputTokens   int       `json:"output_tokens"`
+	LatencyMS      int64     `json:"latency_ms"`
+	Outcome        string    `json:"outcome"`
+	Error          string    `json:"error,omitempty"`
+	Prompt         string    `json:"prompt,omitempty"`
+	Response       string    `json:"response,omitempty"`
+	// BodiesEncrypted is set if Prompt and Response hold base64 encoded envelope
+	// ciphertext, see DecryptBodies
+	BodiesEncrypted bool `json:"bodies_encrypted,omitempty"`
+	// Erased is set when the prompt and response were removed because a candidate of the
+	// entry was erased, see Logger.Forget
+	Erased bool `json:"erased,omitempty"`
+}
+
+// Context returns the command being run and the candidates whose data is being sent
+type Context func(ctx context.Context) (command string, candidates []string)
+
+// Logger appends entries to a JSONL file. The file is opened in append mode for every
+// entry, so existing entries are never rewritten, except to erase a candidate with Forget.
+type Logger struct {
+	mu            sync.Mutex
+	path          string
+	includeBodies bool
+	cipher        *envelope.Cipher
+}
+
+// NewLogger creates a logger that appends to path. With includeBodies the prompt and
+// response text are logged as well, otherwise only their hashes and sizes. With a cipher
+// the logged prompt and response text are encrypted.
+func NewLogger(path string, includeBodies bool, cipher *envelope.Cipher) *Logger {
+	return &Logger{path: path, includeBodies: includeBodies, cipher: cipher}
+}
+
+// Write appends an entry to the log
+func (l *Logger) Write(entry Entry) error {
+	if !l.includeBodies {
+		entry.Prompt = ""
+		entry.Response = ""
+	} else if l.cipher != nil {
+		for _, body := range []*string{&entry.Prompt, &entry.Response} {
+			if *body == "" {
+				continue
+			}
+			sealed, err := l.cipher.Encrypt([]byte(*body))
+			if err != nil {
+				return fmt.Errorf("failed to encrypt audit log entry: %w", err)
+			}
+			*body = base64.StdEncoding.EncodeToString(sealed)
+		}
+		entry.BodiesEncrypted = true
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit log entry: %w", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return nil
+}
+
+// Forget erases candidates from the log and returns the number of entries changed. The
+// prompt and response of every entry that holds their data are removed, and their keys are
+// replaced by a hash, so the log still accounts for the call without identifying them.
+// This is the only time existing entries are rewritten.
+func (l *Logger) Forget(candidates ...string) (int, error) {
+	forget := map[string]bool{}
+	for _, candidate := range candidates {
+		forget[candidate] = true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	data, err := os.ReadFile(l.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read audit log: %w", err)
+	}
+
+	var out bytes.Buffer
+	changed := 0
+	for _, line := range bytes.Split(bytes.TrimRight(data, "\n"), []byte("\n")) {
+		var entry Entry
+		if json.Unmarshal(line, &entry) != nil || !entry.mentions(forget) {
+			out.Write(line)
+			out.WriteByte('\n')
+			continue
+		}
+		for i, candidate := range entry.Candidates {
+			if forget[candidate] {
+				entry.Candidates[i] = "sha256:" + hash([]byte(candidate))
+			}
+		}
+		entry.Prompt, entry.Response, entry.Error = "", "", ""
+		entry.BodiesEncrypted = false
+		entry.Erased = true
+		erased, err := json.Marshal(entry)
+		if err != nil {
+			return 0, fmt.Errorf("failed to encode audit log entry: %w", err)
+		}
+		out.Write(erased)
+		out.WriteByte('\n')
+		changed++
+	}
+	if changed == 0 {
+		return 0, nil
+	}
+
+	// Replace the log in one step, so it is never left half written
+	tmp, err := os.CreateTemp(filepath.Dir(l.path), filepath.Base(l.path)+".*.tmp")
+	if err != nil {
+		return 0, fmt.Errorf("failed to rewrite audit log: %w", err)
+	}
+	_, err = tmp.Write(out.Bytes())
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), l.path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return 0, fmt.Errorf("failed to rewrite audit log: %w", err)
+	}
+	return changed, nil
+}
+
+// mentions reports whether one of the candidates of the entry is in candidates
+func (e Entry) mentions(candidates map[string]bool) bool {
+	for _, candidate := range e.Candidates {
+		if candidates[candidate] {
+			return true
+		}
+	}
+	return false
+}
+
+// DecryptBodies decrypts the prompt and response of an entry written with a cipher
+func DecryptBodies(entry *Entry, cipher *envelope.Cipher) error {
+	if !entry.BodiesEncrypted {
+		return nil
+	}
+	if cipher == nil {
+		return envelope.ErrEncrypted
+	}
+	for _, body := range []*string{&entry.Prompt, &entry.Response} {
+		if *body == "" {
+			continue
+		}
+		sealed, err := base64.StdEncoding.DecodeString(*body)
+		if err != nil {
+			return fmt.Errorf("failed to decode audit log entry: %w", err)
+		}
+		plaintext, err := cipher.Decrypt(sealed)
+		if err != nil {
+			return err
+		}
+		*body = string(plaintext)
+	}
+	entry.BodiesEncrypted = false
+	return nil
+}
+
+// LLMService wraps an LLMService and logs every call
+type LLMService struct {
+	next    interfaces.LLMService
+	logger  *Logger
+	model   string
+	context Context
+}
+
+// NewLLMService creates a new instance of LLMService
+func NewLLMService(next interfaces.LLMService, logger *Logger, model string, context Context) interfaces.LLMService {
+	return &LLMService{next: next, logger: logger, model: model, context: context}
+}
+
+// GenerateText implements the LLMService interface
+func (s *LLMService) GenerateText(prompt string) (string, error) {
+	text, _, err := s.GenerateTextWithUsage(context.Background(), prompt)
+	return text, err
+}
+
+// GenerateTextWithUsage implements the UsageLLMService interface
+func (s *LLMService) GenerateTextWithUsage(ctx context.Context, prompt string) (string, interfaces.TokenUsage, error) {
+	started := time.Now()
+	response, usage, err := interfaces.GenerateTextWithUsage(ctx, s.next, prompt)
+
+	command, candidates := s.context(ctx)
+	entry := Entry{
+		Time:         started.UTC(),
+		Command:      command,
+		Candidates:   candidates,
+		Service:      "llm",
+		Model:        s.model,
+		PromptSHA256: hash([]byte(prompt)),
+		InputTokens:  usage.InputTokens,
+		OutputTokens: usage.OutputTokens,
+		LatencyMS:    time.Since(started).Milliseconds(),
+		Outcome:      outcome(err),
+		Prompt:       prompt,
+		Response:     response,
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	if logErr := s.logger.Write(entry); logErr != nil {
+		// A call that cannot be accounted for is not allowed to pass silently
+		return "", usage, logErr
+	}
+	return response, usage, err
+}
+
+// OCRService wraps an OCRService and logs every call
+type OCRService struct {
+	next    interfaces.OCRService
+	logger  *Logger
+	model   string
+	context Context
+}
+
+// NewOCRService creates a new instance of OCRService
+func NewOCRService(next interfaces.OCRService, logger *Logger, model string, context Context) interfaces.OCRService {
+	return &OCRService{next: next, logger: logger, model: model, context: context}
+}
+
+// ExtractTextFromPDF implements the OCRService interface
+func (s *OCRService) ExtractTextFromPDF(pdfPath string) (string, error) {
+	started := time.Now()
+	text, err := s.next.ExtractTextFromPDF(pdfPath)
+
+	command, _ := s.context(context.Background())
+	entry := Entry{
+		Time:       started.UTC(),
+		Command:    command,
+		Candidates: []string{strings.TrimSuffix(filepath.Base(pdfPath), filepath.Ext(pdfPath))},
+		Service:    "ocr",
+		Model:      s.model,
+		LatencyMS:  time.Since(started).Milliseconds(),
+		Outcome:    outcome(err),
+		Response:   text,
+	}
+	if documentHash, hashErr := hashFile(pdfPath); hashErr == nil {
+		entry.DocumentSHA256 = documentHash
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	if logErr := s.logger.Write(entry); logErr != nil {
+		return "", logErr
+	}
+	return text, err
+}
+
+func outcome(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "ok"
+}
+
+func hash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,343 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nicoalimin/resume-analyzer/interfaces"
+	"github.com/nicoalimin/resume-analyzer/modules/crypto/envelope"
+)
+
+// Kinds of cached responses
+const (
+	KindLLM = "llm"
+	KindOCR = "ocr"
+)
+
+// Entry is one cached response
+type Entry struct {
+	Model      string                `json:"model"`
+	Candidates []string              `json:"candidates"`
+	Created    time.Time             `json:"created"`
+	Usage      interfaces.TokenUsage `json:"usage"`
+	Response   string                `json:"response"`
+}
+
+// KindStats describes the cached responses of one kind
+type KindStats struct {
+	Entries int
+	Expired int
+	Bytes   int64
+}
+
+// Store keeps cached responses as one JSON file per entry in a folder per kind. Entries
+// hold candidate data, so they are written readable only by the current user and are
+// encrypted when a cipher is given.
+type Store struct {
+	dir    string
+	ttl    time.Duration
+	cipher *envelope.Cipher
+
+	mu     sync.Mutex
+	hits   int
+	misses int
+}
+
+// NewStore creates a store in dir. Entries older than ttl are ignored; a ttl of 0 keeps
+// entries forever. cipher may be nil.
+func NewStore(dir string, ttl time.Duration, cipher *envelope.Cipher) *Store {
+	return &Store{dir: dir, ttl: ttl, cipher: cipher}
+}
+
+// Key returns the cache key for the given parts, e.g. model ID, parameters and prompt
+func Key(parts ...string) string {
+	h := sha256.New()
+	for _, part := range parts {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Get returns the entry for key if it exists and has not expired
+func (s *Store) Get(kind string, key string) (Entry, bool) {
+	entry, err := s.read(s.path(kind, key))
+	found := err == nil && !s.expired(entry)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if found {
+		s.hits++
+	} else {
+		s.misses++
+	}
+	return entry, found
+}
+
+// Put stores the entry for key
+func (s *Store) Put(kind string, key string, entry Entry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode cache entry: %w", err)
+	}
+	if s.cipher != nil {
+		if data, err = s.cipher.Encrypt(data); err != nil {
+			return err
+		}
+	}
+
+	path := s.path(kind, key)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create cache folder: %w", err)
+	}
+	// Write to a temporary file of its own first, so a concurrent reader never sees half an
+	// entry and concurrent writers of the same key do not write into each other's file
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	return nil
+}
+
+// Hits returns the number of lookups that were and were not answered from the cache
+func (s *Store) Hits() (hits int, misses int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.hits, s.misses
+}
+
+// Stats returns the number and size of cached entries per kind
+func (s *Store) Stats() (map[string]KindStats, error) {
+	stats := map[string]KindStats{}
+	err := s.walk(func(kind string, path string, info fs.FileInfo) error {
+		kindStats := stats[kind]
+		kindStats.Entries++
+		kindStats.Bytes += info.Size()
+		if entry, err := s.read(path); err != nil || s.expired(entry) {
+			kindStats.Expired++
+		}
+		stats[kind] = kindStats
+		return nil
+	})
+	return stats, err
+}
+
+// Clear removes every entry, or only the expired and unreadable ones, and returns the
+// number of entries removed
+func (s *Store) Clear(expiredOnly bool) (int, error) {
+	removed := 0
+	err := s.walk(func(kind string, path string, info fs.FileInfo) error {
+		if expiredOnly {
+			if entry, err := s.read(path); err == nil && !s.expired(entry) {
+				return nil
+			}
+		}
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		removed++
+		return nil
+	})
+	return removed, err
+}
+
+// Forget removes every entry that holds data of one of the candidates and returns the
+// number of entries removed. Entries that cannot be read are removed as well, since it
+// cannot be ruled out that they hold the candidates' data.
+func (s *Store) Forget(candidates ...string) (int, error) {
+	forget := map[string]bool{}
+	for _, candidate := range candidates {
+		forget[candidate] = true
+	}
+
+	removed := 0
+	err := s.walk(func(kind string, path string, info fs.FileInfo) error {
+		entry, err := s.read(path)
+		matches := err != nil
+		for _, candidate := range entry.Candidates {
+			matches = matches || forget[candidate]
+		}
+		if !matches {
+			return nil
+		}
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		removed++
+		return nil
+	})
+	return removed, err
+}
+
+func (s *Store) path(kind string, key string) string {
+	return filepath.Join(s.dir, kind, key+".json")
+}
+
+func (s *Store) expired(entry Entry) bool {
+	return s.ttl > 0 && time.Since(entry.Created) > s.ttl
+}
+
+func (s *Store) read(path string) (Entry, error) {
+	var entry Entry
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return entry, err
+	}
+	if envelope.IsEncrypted(data) {
+		if s.cipher == nil {
+			return entry, envelope.ErrEncrypted
+		}
+		if data, err = s.cipher.Decrypt(data); err != nil {
+			return entry, err
+		}
+	}
+	err = json.Unmarshal(data, &entry)
+	return entry, err
+}
+
+// walk calls fn for every entry in the store
+func (s *Store) walk(fn func(kind string, path string, info fs.FileInfo) error) error {
+	err := filepath.WalkDir(s.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(path, ".json") {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		return fn(filepath.Base(filepath.Dir(path)), path, info)
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// Context returns the command being run and the candidates whose data is being sent
+type Context func(ctx context.Context) (command string, candidates []string)
+
+// LLMService wraps an LLMService and answers repeated prompts from the cache. Cached
+// answers report no token usage, since no tokens were billed for them.
+type LLMService struct {
+	next    interfaces.LLMService
+	store   *Store
+	model   string
+	params  string
+	context Context
+}
+
+// NewLLMService creates a new instance of LLMService. model and params are part of the
+// cache key, so changing either of them never returns a stale response.
+func NewLLMService(next interfaces.LLMService, store *Store, model string, params string, context Context) interfaces.LLMService {
+	return &LLMService{next: next, store: store, model: model, params: params, context: context}
+}
+
+// GenerateText implements the LLMService interface
+func (s *LLMService) GenerateText(prompt string) (string, error) {
+	text, _, err := s.GenerateTextWithUsage(context.Background(), prompt)
+	return text, err
+}
+
+// GenerateTextWithUsage implements the UsageLLMService interface
+func (s *LLMService) GenerateTextWithUsage(ctx context.Context, prompt string) (string, interfaces.TokenUsage, error) {
+	parts := []string{s.model, s.params}
+	if team := interfaces.CallFrom(ctx).Team; team != "" {
+		// Teams never get each other's cached responses
+		parts = append(parts, "team:"+team)
+	}
+	key := Key(append(parts, prompt)...)
+	if entry, ok := s.store.Get(KindLLM, key); ok {
+		return entry.Response, interfaces.TokenUsage{}, nil
+	}
+
+	text, usage, err := interfaces.GenerateTextWithUsage(ctx, s.next, prompt)
+	if err != nil {
+		return text, usage, err
+	}
+	_, candidates := s.context(ctx)
+	// A failed write only means the call is made again next time
+	s.store.Put(KindLLM, key, Entry{
+		Model:      s.model,
+		Candidates: candidates,
+		Created:    time.Now().UTC(),
+		Usage:      usage,
+		Response:   text,
+	})
+	return text, usage, nil
+}
+
+// OCRService wraps an OCRService and answers repeated documents from the cache, keyed by
+// the hash of the PDF
+type OCRService struct {
+	next  interfaces.OCRService
+	store *Store
+	model string
+}
+
+// NewOCRService creates a new instance of OCRService
+func NewOCRService(next interfaces.OCRService, store *Store, model string) interfaces.OCRService {
+	return &OCRService{next: next, store: store, model: model}
+}
+
+// ExtractTextFromPDF implements the OCRService interface
+func (s *OCRService) ExtractTextFromPDF(pdfPath string) (string, error) {
+	documentHash, err := hashFile(pdfPath)
+	if err != nil {
+		return s.next.ExtractTextFromPDF(pdfPath)
+	}
+	key := Key(s.model, documentHash)
+	if entry, ok := s.store.Get(KindOCR, key); ok {
+		return entry.Response, nil
+	}
+
+	text, err := s.next.ExtractTextFromPDF(pdfPath)
+	if err != nil {
+		return text, err
+	}
+	s.store.Put(KindOCR, key, Entry{
+		Model:      s.model,
+		Candidates: []string{strings.TrimSuffix(filepath.Base(pdfPath), filepath.Ext(pdfPath))},
+		Created:    time.Now().UTC(),
+		Response:   text,
+	})
+	return text, nil
+}
+
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,143 @@
+package envelope
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// KeySize is the size of the master key and of every data key in bytes (AES-256)
+const KeySize = 32
+
+// magic marks encrypted files. It is also authenticated with every ciphertext.
+var magic = []byte("RAENC1\n")
+
+// ErrEncrypted is returned when encrypted data is read without a key
+var ErrEncrypted = errors.New("data is encrypted and no encryption key is configured")
+
+// Cipher encrypts data with envelope encryption: every call generates a fresh AES-256-GCM
+// data key, and only that data key is encrypted with the master key.
+//
+// The format is: magic | key nonce | encrypted data key | data nonce | encrypted data
+type Cipher struct {
+	master cipher.AEAD
+}
+
+// NewCipher creates a Cipher for a 32-byte master key
+func NewCipher(key []byte) (*Cipher, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("encryption key must be %d bytes, got %d", KeySize, len(key))
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	return &Cipher{master: aead}, nil
+}
+
+// GenerateKey returns a new random master key, base64 encoded
+func GenerateKey() (string, error) {
+	key := make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return "", fmt.Errorf("failed to generate key: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// ParseKey decodes a base64 or hex encoded master key
+func ParseKey(encoded string) ([]byte, error) {
+	encoded = strings.TrimSpace(encoded)
+	if key, err := base64.StdEncoding.DecodeString(encoded); err == nil && len(key) == KeySize {
+		return key, nil
+	}
+	if key, err := hex.DecodeString(encoded); err == nil && len(key) == KeySize {
+		return key, nil
+	}
+	return nil, fmt.Errorf("encryption key must be %d bytes encoded as base64 or hex", KeySize)
+}
+
+// IsEncrypted reports whether data was written by Encrypt
+func IsEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, magic)
+}
+
+// Encrypt encrypts plaintext under a new data key
+func (c *Cipher) Encrypt(plaintext []byte) ([]byte, error) {
+	dataKey := make([]byte, KeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, fmt.Errorf("failed to generate data key: %w", err)
+	}
+	data, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	keyNonce, err := nonce(c.master)
+	if err != nil {
+		return nil, err
+	}
+	dataNonce, err := nonce(data)
+	if err != nil {
+		return nil, err
+	}
+
+	out := append([]byte{}, magic...)
+	out = append(out, keyNonce...)
+	out = c.master.Seal(out, keyNonce, dataKey, magic)
+	out = append(out, dataNonce...)
+	out = data.Seal(out, dataNonce, plaintext, magic)
+	return out, nil
+}
+
+// Decrypt decrypts data written by Encrypt. Data without the encryption header is returned
+// unchanged, so plaintext files from before encryption was enabled can still be read.
+func (c *Cipher) Decrypt(ciphertext []byte) ([]byte, error) {
+	if !IsEncrypted(ciphertext) {
+		return ciphertext, nil
+	}
+	rest := ciphertext[len(magic):]
+
+	nonceSize := c.master.NonceSize()
+	wrappedSize := KeySize + c.master.Overhead()
+	if len(rest) < 2*nonceSize+wrappedSize {
+		return nil, errors.New("encrypted data is truncated")
+	}
+
+	dataKey, err := c.master.Open(nil, rest[:nonceSize], rest[nonceSize:nonceSize+wrappedSize], magic)
+	if err != nil {
+		return nil, errors.New("failed to decrypt data key: wrong encryption key or corrupted data")
+	}
+	rest = rest[nonceSize+wrappedSize:]
+
+	data, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := data.Open(nil, rest[:nonceSize], rest[nonceSize:], magic)
+	if err != nil {
+		return nil, errors.New("failed to decrypt data: corrupted data")
+	}
+	return plaintext, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+func nonce(aead cipher.AEAD) ([]byte, error) {
+	n := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(n); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return n, nil
+}
@@ -0,0 +1,186 @@
+package sqlite
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nicoalimin/resume-analyzer/interfaces"
+	_ "modernc.org/sqlite"
+)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS api_keys (
+	id           TEXT PRIMARY KEY,
+	name         TEXT NOT NULL,
+	team         TEXT NOT NULL,
+	prefix       TEXT NOT NULL,
+	hash         TEXT NOT NULL UNIQUE,
+	created_at   TEXT NOT NULL,
+	last_used_at TEXT,
+	revoked_at   TEXT
+);
+`
+
+// keyPrefix starts every secret key, so leaked keys are easy to recognize
+const keyPrefix = "ra_"
+
+// lastUsedInterval limits how often the last use of a key is written
+const lastUsedInterval = time.Minute
+
+// SQLiteKeyStore implements the KeyStore interface using a local SQLite database. Secret keys
+// are random, so their SHA-256 hash is stored and looked up instead of the key itself.
+type SQLiteKeyStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteKeyStore opens (and if needed creates) the SQLite key store at path
+func NewSQLiteKeyStore(path string) (interfaces.KeyStore, error) {
+	db, err := sql.Open("sqlite", path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open key store: %w", err)
+	}
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create schema: %w", err)
+	}
+	return &SQLiteKeyStore{db: db}, nil
+}
+
+// Create implements the KeyStore interface
+func (s *SQLiteKeyStore) Create(team string, name string) (interfaces.APIKey, string, error) {
+	id, err := randomHex(8)
+	if err != nil {
+		return interfaces.APIKey{}, "", err
+	}
+	random, err := randomHex(24)
+	if err != nil {
+		return interfaces.APIKey{}, "", err
+	}
+	secret := keyPrefix + random
+
+	key := interfaces.APIKey{
+		ID:      id,
+		Name:    name,
+		Team:    team,
+		Prefix:  secret[:len(keyPrefix)+6],
+		Created: time.Now().UTC().Truncate(time.Second),
+	}
+	_, err = s.db.Exec(
+		`INSERT INTO api_keys (id, name, team, prefix, hash, created_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		key.ID, key.Name, key.Team, key.Prefix, hash(secret), key.Created.Format(time.RFC3339),
+	)
+	if err != nil {
+		return interfaces.APIKey{}, "", fmt.Errorf("failed to create API key: %w", err)
+	}
+	return key, secret, nil
+}
+
+// Authenticate implements the KeyStore interface
+func (s *SQLiteKeyStore) Authenticate(secret string) (*interfaces.APIKey, error) {
+	if secret == "" {
+		return nil, nil
+	}
+	key, err := s.scan(s.db.QueryRow(`SELECT `+columns+` FROM api_keys WHERE hash = ? AND revoked_at IS NULL`, hash(secret)))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read API key: %w", err)
+	}
+
+	now := time.Now().UTC()
+	if key.LastUsed == nil || now.Sub(*key.LastUsed) >= lastUsedInterval {
+		if _, err := s.db.Exec(`UPDATE api_keys SET last_used_at = ? WHERE id = ?`, now.Format(time.RFC3339), key.ID); err != nil {
+			return nil, fmt.Errorf("failed to update API key: %w", err)
+		}
+	}
+	return &key, nil
+}
+
+// Revoke implements the KeyStore interface
+func (s *SQLiteKeyStore) Revoke(id string) (interfaces.APIKey, error) {
+	_, err := s.db.Exec(`UPDATE api_keys SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL`, time.Now().UTC().Format(time.RFC3339), id)
+	if err != nil {
+		return interfaces.APIKey{}, fmt.Errorf("failed to revoke API key: %w", err)
+	}
+	key, err := s.scan(s.db.QueryRow(`SELECT `+columns+` FROM api_keys WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return interfaces.APIKey{}, interfaces.ErrKeyNotFound
+	}
+	if err != nil {
+		return interfaces.APIKey{}, fmt.Errorf("failed to read API key: %w", err)
+	}
+	return key, nil
+}
+
+// List implements the KeyStore interface
+func (s *SQLiteKeyStore) List() ([]interfaces.APIKey, error) {
+	rows, err := s.db.Query(`SELECT ` + columns + ` FROM api_keys ORDER BY created_at, rowid`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list API keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []interfaces.APIKey{}
+	for rows.Next() {
+		key, err := s.scan(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read API key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list API keys: %w", err)
+	}
+	return keys, nil
+}
+
+// Close implements the KeyStore interface
+func (s *SQLiteKeyStore) Close() error {
+	return s.db.Close()
+}
+
+const columns = `id, name, team, prefix, created_at, last_used_at, revoked_at`
+
+// scan reads a row selected with columns
+func (s *SQLiteKeyStore) scan(row interface{ Scan(...any) error }) (interfaces.APIKey, error) {
+	var key interfaces.APIKey
+	var created string
+	var lastUsed, revoked sql.NullString
+	if err := row.Scan(&key.ID, &key.Name, &key.Team, &key.Prefix, &created, &lastUsed, &revoked); err != nil {
+		return interfaces.APIKey{}, err
+	}
+	key.Created, _ = time.Parse(time.RFC3339, created)
+	key.LastUsed = parseTime(lastUsed)
+	key.Revoked = parseTime(revoked)
+	return key, nil
+}
+
+func parseTime(value sql.NullString) *time.Time {
+	if !value.Valid {
+		return nil
+	}
+	t, err := time.Parse(time.RFC3339, value.String)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
+
+func hash(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(size int) (string, error) {
+	data := make([]byte, size)
+	if _, err := rand.Read(data); err != nil {
+		return "", fmt.Errorf("failed to generate API key: %w", err)
+	}
+	return hex.EncodeToString(data), nil
+}
@@ -12,8 +12,6 @@ import (
 	"github.com/spf13/viper"
 )
 
-var ctx = context.Background()
-
 // SummaryRequest represents the request structure for Claude
 type SummaryRequest struct {
 	Messages         []Message `json:"messages"`
@@ -31,6 +29,13 @@ type Message struct {
 // SummaryResponse represents the response structure from Claude
 type SummaryResponse struct {
 	Content []Content `json:"content"`
+	Usage   Usage     `json:"usage"`
+}
+
+// Usage represents the token counts reported in the response
+type Usage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
 }
 
 // Content represents the content in the response
@@ -47,17 +52,23 @@ func NewBedrockService() interfaces.LLMService {
 	return &BedrockService{}
 }
 
-// GenerateText implements the LLMService interface
-func (b *BedrockService) GenerateText(prompt string) (string, error) {
-	// Load AWS config
-	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion("ap-southeast-1"))
-	if err != nil {
-		return "", fmt.Errorf("failed to load AWS config: %w", err)
+// ModelID returns the configured Bedrock model ID, or the default model if none is configured
+func ModelID() string {
+	if modelID := viper.GetString("bedrock_model_id"); modelID != "" {
+		return modelID
 	}
+	return "anthropic.claude-3-5-sonnet-20240620-v1:0"
+}
 
-	client := bedrockruntime.NewFromConfig(cfg)
+// Parameters returns the generation parameters sent with every prompt, so cached responses
+// can be told apart when they change
+func Parameters() string {
+	request := newRequest("")
+	return fmt.Sprintf("max_tokens=%d temperature=%g anthropic_version=%s",
+		request.MaxTokens, request.Temperature, request.AnthropicVersion)
+}
 
-	// Create the request
+func newRequest(prompt string) SummaryRequest {
 	request := SummaryRequest{
 		Messages: []Message{
 			{
@@ -72,38 +83,64 @@ func (b *BedrockService) GenerateText(prompt string) (string, error) {
 	if request.AnthropicVersion == "" {
 		request.AnthropicVersion = "bedrock-2023-05-31"
 	}
+	return request
+}
+
+// GenerateText implements the LLMService interface
+func (b *BedrockService) GenerateText(prompt string) (string, error) {
+	text, _, err := b.GenerateTextWithUsage(context.Background(), prompt)
+	return text, err
+}
+
+// GenerateTextWithUsage implements the UsageLLMService interface
+func (b *BedrockService) GenerateTextWithUsage(ctx context.Context, prompt string) (string, interfaces.TokenUsage, error) {
+	var usage interfaces.TokenUsage
+
+	// Load AWS config
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion("ap-southeast-1"))
+	if err != nil {
+		return "", usage, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+
+	client := bedrockruntime.NewFromConfig(cfg)
+
+	// Create the request
+	request := newRequest(prompt)
 
 	// Marshal the request to JSON
 	requestBytes, err := json.Marshal(request)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
+		return "", usage, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
 	// Call Bedrock (using Claude 3 Sonnet)
 	input := &bedrockruntime.InvokeModelInput{
-		ModelId:     aws.String(viper.GetString("bedrock_model_id")),
+		ModelId:     aws.String(ModelID()),
 		ContentType: aws.String("application/json"),
 		Body:        requestBytes,
 	}
-	if *input.ModelId == "" {
-		*input.ModelId = "anthropic.claude-3-5-sonnet-20240620-v1:0"
-	}
 
 	resp, err := client.InvokeModel(ctx, input)
 	if err != nil {
-		return "", fmt.Errorf("bedrock invoke failed: %w", err)
+		return "", usage, fmt.Errorf("bedrock invoke failed: %w", err)
 	}
 
 	// Parse the response
 	var summaryResp SummaryResponse
 	err = json.Unmarshal(resp.Body, &summaryResp)
 	if err != nil {
-		return "", fmt.Errorf("failed to unmarshal response: %w", err)
+		return "", usage, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	// The tokens are billed even if the response holds no content
+	usage = interfaces.TokenUsage{
+		InputTokens:  summaryResp.Usage.InputTokens,
+		OutputTokens: summaryResp.Usage.OutputTokens,
 	}
 
 	if len(summaryResp.Content) == 0 {
-		return "", fmt.Errorf("no content in response")
+		return "", usage, fmt.Errorf("no content in response")
 	}
 
-	return summaryResp.Content[0].Text, nil
+	return summaryResp.Content[0].Text, usage, nil
 }
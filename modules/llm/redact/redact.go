@@ -0,0 +1,350 @@
+package redact
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/nicoalimin/resume-analyzer/interfaces"
+)
+
+// Categories of personal data that are replaced by placeholder tokens
+const (
+	CategoryName    = "NAME"
+	CategoryEmail   = "EMAIL"
+	CategoryPhone   = "PHONE"
+	CategoryAddress = "ADDRESS"
+	CategoryDOB     = "DOB"
+	CategoryID      = "ID"
+)
+
+// Pattern detects one kind of personal data. If the expression has a capture group,
+// only the first group is replaced, which allows matching on labels like "Address:".
+type Pattern struct {
+	Category string
+	Regexp   *regexp.Regexp
+}
+
+// DefaultPatterns are the built-in detectors for personal data in resumes
+var DefaultPatterns = []Pattern{
+	{CategoryEmail, regexp.MustCompile(`(?i)[a-z0-9._%+\-]+@[a-z0-9.\-]+\.[a-z]{2,}`)},
+	{CategoryDOB, regexp.MustCompile(`(?i)\b(?:date of birth|d\.?o\.?b\.?|born(?: on)?)\s*[:\-]?\s*(\d{1,2}[\s/.\-][a-z0-9]{1,9}[\s/.\-]\d{2,4}|[a-z]{3,9}\.? \d{1,2},? \d{4}|\d{4}-\d{2}-\d{2})`)},
+	{CategoryID, regexp.MustCompile(`\b[STFGM]\d{7}[A-Z]\b`)},
+	{CategoryID, regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`)},
+	{CategoryID, regexp.MustCompile(`(?i)\b(?:passport|nric|fin|national id|ic|id)(?: no\.?| number)?\s*[:#]\s*([a-z0-9\-]{6,})`)},
+	{CategoryAddress, regexp.MustCompile(`(?i)\b(?:address|residence)\s*[:\-]\s*([^\n]+)`)},
+	{CategoryAddress, regexp.MustCompile(`\b\d{1,5}[A-Za-z]?,? (?:[A-Z][a-z]+ ){1,4}(?:Street|St|Road|Rd|Avenue|Ave|Lane|Ln|Drive|Dr|Boulevard|Blvd|Crescent|Cres|Close|Way|Place)\b\.?[^\n]{0,60}`)},
+	{CategoryName, regexp.MustCompile(`(?m)^[\W\d]*(?i:full )?(?i:name)\**\s*[:\-]\s*\**\s*([A-Z][A-Za-z'\-]+(?: [A-Z][A-Za-z'\-]+){1,3})`)},
+	{CategoryPhone, regexp.MustCompile(`\(?\+?\d[\d\s().\-]{7,}\d`)},
+}
+
+var yearRangePattern = regexp.MustCompile(`^(19|20)\d\d\s*[-–.]\s*(19|20)\d\d$`)
+var datePattern = regexp.MustCompile(`^(?:\d{1,2}[./\-]\d{1,2}[./\-](?:19|20)\d\d|(?:19|20)\d\d[./\-]\d{1,2}[./\-]\d{1,2})$`)
+var namePattern = regexp.MustCompile(`^(?:[A-Z][A-Za-z'\-]+|[A-Z]\.)(?: (?:[A-Z][A-Za-z'\-]+|[A-Z]\.)){1,3}$`)
+
+// nonNames are resume headings that look like a name on the first line
+var nonNames = map[string]bool{"resume": true, "curriculum vitae": true, "cv": true, "profile": true}
+var tokenPattern = regexp.MustCompile(`\[?\b([A-Z][A-Z0-9_]*)_(\d+)\b\]?`)
+
+// CategoryPattern is the form of category names, which are part of the placeholder tokens
+var CategoryPattern = regexp.MustCompile(`^[A-Z][A-Z0-9_]*$`)
+
+// mappingFile is the on-disk format of the placeholder mapping
+type mappingFile struct {
+	Values map[string]string `json:"values"`
+}
+
+// Redactor replaces personal data with placeholder tokens like [EMAIL_1] and restores them.
+// The same value always gets the same token, and the mapping is kept in a local file so
+// results can be re-identified after the run.
+type Redactor struct {
+	mu       sync.Mutex
+	files    interfaces.FileStore
+	path     string
+	patterns []Pattern
+	tokens   map[string]string // normalized value -> token
+	values   map[string]string // token -> original value
+	counters map[string]int
+}
+
+// NewRedactor creates a redactor that keeps its mapping in path, loading any existing mapping.
+// The mapping is read and written through files, e.g. to encrypt it at rest.
+func NewRedactor(files interfaces.FileStore, path string, patterns []Pattern) (*Redactor, error) {
+	r := &Redactor{
+		files:    files,
+		path:     path,
+		patterns: patterns,
+		tokens:   map[string]string{},
+		values:   map[string]string{},
+		counters: map[string]int{},
+	}
+
+	data, err := files.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read redaction mapping: %w", err)
+	}
+
+	var mapping mappingFile
+	if err := json.Unmarshal(data, &mapping); err != nil {
+		return nil, fmt.Errorf("failed to parse redaction mapping: %w", err)
+	}
+	for token, value := range mapping.Values {
+		match := tokenPattern.FindStringSubmatch(token)
+		if match == nil {
+			continue
+		}
+		number, _ := strconv.Atoi(match[2])
+		r.values[token] = value
+		r.tokens[match[1]+":"+normalize(value)] = token
+		r.counters[match[1]] = max(r.counters[match[1]], number)
+	}
+	return r, nil
+}
+
+// ApplicantName returns the name on the first line of a resume, or "" if the first line
+// does not look like a name. Resumes almost always start with the name on a line of its own.
+func ApplicantName(text string) string {
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.Join(strings.Fields(line), " ")
+		if line == "" {
+			continue
+		}
+		if namePattern.MatchString(line) && !nonNames[normalize(line)] {
+			return line
+		}
+		return ""
+	}
+	return ""
+}
+
+// Redact replaces all detected personal data in text with placeholder tokens. Names found
+// by the patterns, and the given names of the applicants whose resumes are in text, are
+// replaced wherever they appear in text. Names are not remembered for later calls, so one
+// applicant's name never leaks into the redaction of another's resume.
+func (r *Redactor) Redact(text string, names ...string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	known := map[string]string{} // normalized name or name part -> name
+	for _, name := range names {
+		addName(known, name)
+	}
+	for _, pattern := range r.patterns {
+		text = pattern.Regexp.ReplaceAllStringFunc(text, func(match string) string {
+			sub := pattern.Regexp.FindStringSubmatchIndex(match)
+			start, end := 0, len(match)
+			if len(sub) >= 4 && sub[2] >= 0 {
+				start, end = sub[2], sub[3]
+			}
+			value := strings.TrimSpace(match[start:end])
+			if value == "" || tokenPattern.MatchString(value) {
+				return match
+			}
+			if pattern.Category == CategoryPhone && !isPhone(value) {
+				return match
+			}
+			if pattern.Category == CategoryName {
+				addName(known, value)
+			}
+			return match[:start] + r.tokenFor(pattern.Category, value) + match[end:]
+		})
+	}
+	if len(known) == 0 {
+		return text
+	}
+
+	// Known names are replaced last in one pass. Longer names come first in the alternation,
+	// so "Jane Doe" wins over "Jane".
+	alternatives := make([]string, 0, len(known))
+	for _, name := range known {
+		alternatives = append(alternatives, name)
+	}
+	sort.Slice(alternatives, func(i, j int) bool {
+		if len(alternatives[i]) != len(alternatives[j]) {
+			return len(alternatives[i]) > len(alternatives[j])
+		}
+		return alternatives[i] < alternatives[j]
+	})
+	for i, name := range alternatives {
+		alternatives[i] = regexp.QuoteMeta(name)
+	}
+	re := regexp.MustCompile(`(?i)\b(?:` + strings.Join(alternatives, "|") + `)\b`)
+	return re.ReplaceAllStringFunc(text, func(match string) string {
+		return r.tokenFor(CategoryName, known[normalize(match)])
+	})
+}
+
+// Restore replaces placeholder tokens in text with the original values.
+// Models sometimes drop the brackets, so bare tokens like NAME_1 are restored as well.
+func (r *Redactor) Restore(text string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return tokenPattern.ReplaceAllStringFunc(text, func(match string) string {
+		sub := tokenPattern.FindStringSubmatch(match)
+		if value, ok := r.values["["+sub[1]+"_"+sub[2]+"]"]; ok {
+			return value
+		}
+		return match
+	})
+}
+
+// RestoreFrom restores only the placeholder tokens that occur in prompt. A response then
+// never reveals values of another call, e.g. of another team's candidates, even if the
+// model makes up a token that belongs to them.
+func (r *Redactor) RestoreFrom(text string, prompt string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sent := map[string]bool{}
+	for _, sub := range tokenPattern.FindAllStringSubmatch(prompt, -1) {
+		sent["["+sub[1]+"_"+sub[2]+"]"] = true
+	}
+	return tokenPattern.ReplaceAllStringFunc(text, func(match string) string {
+		sub := tokenPattern.FindStringSubmatch(match)
+		token := "[" + sub[1] + "_" + sub[2] + "]"
+		if value, ok := r.values[token]; ok && sent[token] {
+			return value
+		}
+		return match
+	})
+}
+
+// Save writes the mapping to the local mapping file, readable only by the current user
+func (r *Redactor) Save() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	data, err := json.MarshalIndent(mappingFile{Values: r.values}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode redaction mapping: %w", err)
+	}
+	if err := r.files.WriteFile(r.path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write redaction mapping: %w", err)
+	}
+	return nil
+}
+
+// Forget removes every mapping entry whose value is one of values and returns how many were removed
+func (r *Redactor) Forget(values ...string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	forget := map[string]bool{}
+	for _, value := range values {
+		if value = normalize(value); value != "" {
+			forget[value] = true
+		}
+	}
+
+	removed := 0
+	for token, value := range r.values {
+		category := tokenPattern.FindStringSubmatch(token)[1]
+		if !forget[normalize(value)] {
+			continue
+		}
+		delete(r.values, token)
+		delete(r.tokens, category+":"+normalize(value))
+		removed++
+	}
+	return removed
+}
+
+// addName adds a full name and its individual parts to known
+func addName(known map[string]string, name string) {
+	name = strings.Join(strings.Fields(name), " ")
+	if name == "" {
+		return
+	}
+	known[normalize(name)] = name
+	for _, part := range strings.Fields(name) {
+		part = strings.Trim(part, ".")
+		if len(part) >= 3 {
+			known[normalize(part)] = part
+		}
+	}
+}
+
+// tokenFor returns the placeholder for a value, creating one if needed
+func (r *Redactor) tokenFor(category string, value string) string {
+	key := category + ":" + normalize(value)
+	if token, ok := r.tokens[key]; ok {
+		return token
+	}
+	r.counters[category]++
+	token := fmt.Sprintf("[%s_%d]", category, r.counters[category])
+	r.tokens[key] = token
+	r.values[token] = value
+	return token
+}
+
+// normalize makes lookups insensitive to case and whitespace
+func normalize(value string) string {
+	return strings.ToLower(strings.Join(strings.Fields(value), " "))
+}
+
+// isPhone filters phone pattern matches that are really year ranges, dates or short numbers.
+// Employment dates must reach the model to work out the years of experience.
+func isPhone(value string) bool {
+	// The pattern can take in the brackets around a period, e.g. "(2019-2021)"
+	value = strings.Trim(value, "()[] ")
+	if yearRangePattern.MatchString(value) || datePattern.MatchString(value) {
+		return false
+	}
+	digits := 0
+	for _, r := range value {
+		if r >= '0' && r <= '9' {
+			digits++
+		}
+	}
+	return digits >= 8 && digits <= 15
+}
+
+// RedactingService wraps an LLMService so that prompts are redacted before they are sent
+// and placeholder tokens in the response are restored
+type RedactingService struct {
+	next     interfaces.LLMService
+	redactor *Redactor
+}
+
+// NewRedactingService creates a new instance of RedactingService
+func NewRedactingService(next interfaces.LLMService, redactor *Redactor) interfaces.LLMService {
+	return &RedactingService{next: next, redactor: redactor}
+}
+
+// GenerateText implements the LLMService interface
+func (s *RedactingService) GenerateText(prompt string) (string, error) {
+	text, _, err := s.GenerateTextWithUsage(context.Background(), prompt)
+	return text, err
+}
+
+// GenerateTextWithUsage implements the UsageLLMService interface
+func (s *RedactingService) GenerateTextWithUsage(ctx context.Context, prompt string) (string, interfaces.TokenUsage, error) {
+	// The names of the applicants whose resumes are in the call are redacted as well
+	var names []string
+	for _, text := range interfaces.CallFrom(ctx).Texts {
+		if name := ApplicantName(text); name != "" {
+			names = append(names, name)
+		}
+	}
+	redacted := s.redactor.Redact(prompt, names...)
+	if err := s.redactor.Save(); err != nil {
+		return "", interfaces.TokenUsage{}, err
+	}
+
+	response, usage, err := interfaces.GenerateTextWithUsage(ctx, s.next, redacted)
+	if err != nil {
+		return "", usage, err
+	}
+	return s.redactor.RestoreFrom(response, redacted), usage, nil
+}
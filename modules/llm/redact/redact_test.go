@@ -0,0 +1,70 @@
+package redact
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// memFiles keeps the mapping file in memory
+type memFiles map[string][]byte
+
+func (m memFiles) ReadFile(path string) ([]byte, error) {
+	data, ok := m[path]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return data, nil
+}
+
+func (m memFiles) WriteFile(path string, data []byte, perm os.FileMode) error {
+	m[path] = data
+	return nil
+}
+
+func TestIsPhone(t *testing.T) {
+	tests := []struct {
+		value string
+		want  bool
+	}{
+		{"+65 9123 4567", true},
+		{"(+65) 9123-4567", true},
+		{"020 7946 0000", true},
+		{"2019 - 2021", false},
+		{"(2019-2021", false},
+		{"(2019-2021)", false},
+		{"2019–2021", false},
+		{"2019-01-15", false},
+		{"15.01.2019", false},
+		{"123 4567", false},
+	}
+	for _, test := range tests {
+		if got := isPhone(test.value); got != test.want {
+			t.Errorf("isPhone(%q) = %v, want %v", test.value, got, test.want)
+		}
+	}
+}
+
+func TestRedactKeepsDates(t *testing.T) {
+	r, err := NewRedactor(memFiles{}, filepath.Join("map", "redaction-map.json"), DefaultPatterns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := "Jane Doe\nPhone: +65 9123 4567\nAcme (2019-2021), Globex 2021-03-01 to 2023-06-30"
+	redacted := r.Redact(text, "Jane Doe")
+
+	for _, kept := range []string{"(2019-2021)", "2021-03-01", "2023-06-30"} {
+		if !strings.Contains(redacted, kept) {
+			t.Errorf("Redact() = %q, want %q kept", redacted, kept)
+		}
+	}
+	for _, removed := range []string{"Jane", "9123"} {
+		if strings.Contains(redacted, removed) {
+			t.Errorf("Redact() = %q, want %q redacted", redacted, removed)
+		}
+	}
+	if restored := r.Restore(redacted); restored != text {
+		t.Errorf("Restore() = %q, want %q", restored, text)
+	}
+}
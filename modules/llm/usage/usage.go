@@ -0,0 +1,261 @@
+package usage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nicoalimin/resume-analyzer/interfaces"
+)
+
+// ErrBudgetExceeded is returned once the estimated cost of the run, or of the current budget
+// period, reaches the budget
+var ErrBudgetExceeded = errors.New("budget exceeded")
+
+// Price is the price of a model in USD per million tokens
+type Price struct {
+	Model            string  `mapstructure:"model"`
+	InputPerMillion  float64 `mapstructure:"input_per_million"`
+	OutputPerMillion float64 `mapstructure:"output_per_million"`
+}
+
+// DefaultPrices holds the on-demand Bedrock prices of the models the tool is usually run with.
+// Prices in the config file take precedence.
+var DefaultPrices = []Price{
+	{Model: "amazon.nova-micro-v1:0", InputPerMillion: 0.035, OutputPerMillion: 0.14},
+	{Model: "amazon.nova-lite-v1:0", InputPerMillion: 0.06, OutputPerMillion: 0.24},
+	{Model: "amazon.nova-pro-v1:0", InputPerMillion: 0.8, OutputPerMillion: 3.2},
+	{Model: "anthropic.claude-3-haiku-20240307-v1:0", InputPerMillion: 0.25, OutputPerMillion: 1.25},
+	{Model: "anthropic.claude-3-5-sonnet-20240620-v1:0", InputPerMillion: 3, OutputPerMillion: 15},
+}
+
+// Cost returns the estimated cost in USD of the given usage
+func (p Price) Cost(u interfaces.TokenUsage) float64 {
+	return float64(u.InputTokens)*p.InputPerMillion/1e6 + float64(u.OutputTokens)*p.OutputPerMillion/1e6
+}
+
+// Totals is the aggregated usage of a number of calls
+type Totals struct {
+	Calls        int
+	InputTokens  int
+	OutputTokens int
+	Cost         float64
+}
+
+func (t *Totals) add(u interfaces.TokenUsage, cost float64) {
+	t.Calls++
+	t.InputTokens += u.InputTokens
+	t.OutputTokens += u.OutputTokens
+	t.Cost += cost
+}
+
+// Tracker aggregates the token usage and estimated cost of a run per file and per command,
+// and enforces an optional budget
+type Tracker struct {
+	// OnExceeded is called when a call is refused because the budget has been used up,
+	// e.g. to abort the run
+	OnExceeded func(err error)
+	// OnNoUsage is called when a call to a priced model reports no token usage, so its cost
+	// cannot be estimated and the budget is not enforced for it, e.g. to print a warning
+	OnNoUsage func()
+	// Period, if set, starts a new budget period after this long, e.g. 24h for the daily
+	// budget of a long-running server. Without a period the budget applies to the whole run.
+	Period time.Duration
+
+	mu          sync.Mutex
+	price       Price
+	priced      bool
+	budget      float64
+	run         Totals
+	spent       Totals
+	periodStart time.Time
+	byCommand   map[string]*Totals
+	byFile      map[string]*Totals
+	fileOrder   []string
+}
+
+// NewTracker creates a tracker that prices calls to model with the given price table.
+// A budget of 0 means no budget.
+func NewTracker(model string, prices []Price, budget float64) (*Tracker, error) {
+	t := &Tracker{
+		budget:      budget,
+		byCommand:   map[string]*Totals{},
+		byFile:      map[string]*Totals{},
+		periodStart: time.Now(),
+	}
+	for _, price := range prices {
+		if price.Model == model {
+			t.price = price
+			t.priced = true
+			break
+		}
+	}
+	if budget > 0 && !t.priced {
+		return nil, fmt.Errorf("no price configured for model %s, so the budget cannot be enforced", model)
+	}
+	t.price.Model = model
+	return t, nil
+}
+
+// Priced reports whether a price is known for the model, i.e. whether costs are estimated
+func (t *Tracker) Priced() bool {
+	return t.priced
+}
+
+// Exceeded reports whether the budget has been used up
+func (t *Tracker) Exceeded() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.exceeded()
+}
+
+func (t *Tracker) exceeded() bool {
+	t.rollOver()
+	return t.budget > 0 && t.spent.Cost >= t.budget
+}
+
+// rollOver starts a new budget period once the current one has ended
+func (t *Tracker) rollOver() {
+	if t.Period <= 0 || time.Since(t.periodStart) < t.Period {
+		return
+	}
+	t.spent = Totals{}
+	t.periodStart = time.Now()
+}
+
+// Check returns ErrBudgetExceeded and calls OnExceeded if the budget has been used up
+func (t *Tracker) Check() error {
+	t.mu.Lock()
+	if !t.exceeded() {
+		t.mu.Unlock()
+		return nil
+	}
+	err := fmt.Errorf("%w: estimated cost $%.4f of $%.4f budget", ErrBudgetExceeded, t.spent.Cost, t.budget)
+	if t.Period > 0 {
+		err = fmt.Errorf("%w until %s", err, t.periodStart.Add(t.Period).Format(time.RFC3339))
+	}
+	t.mu.Unlock()
+
+	if t.OnExceeded != nil {
+		t.OnExceeded(err)
+	}
+	return err
+}
+
+// Record adds the usage of one call made by command for the given files
+func (t *Tracker) Record(command string, files []string, u interfaces.TokenUsage) {
+	cost := t.price.Cost(u)
+
+	t.mu.Lock()
+	t.rollOver()
+	t.run.add(u, cost)
+	t.spent.add(u, cost)
+	if t.byCommand[command] == nil {
+		t.byCommand[command] = &Totals{}
+	}
+	t.byCommand[command].add(u, cost)
+
+	file := "(no file)"
+	if len(files) == 1 {
+		file = files[0]
+	} else if len(files) > 1 {
+		file = fmt.Sprintf("(%d files)", len(files))
+	}
+	if t.byFile[file] == nil {
+		t.byFile[file] = &Totals{}
+		t.fileOrder = append(t.fileOrder, file)
+	}
+	t.byFile[file].add(u, cost)
+	t.mu.Unlock()
+}
+
+// Run returns the totals of the whole run
+func (t *Tracker) Run() Totals {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.run
+}
+
+// Summary returns a printable report of the usage per file, per command and for the run
+func (t *Tracker) Summary() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Token usage (%s):\n", t.price.Model)
+	for _, file := range t.fileOrder {
+		fmt.Fprintf(&b, "  %-40s %s\n", file, t.format(*t.byFile[file]))
+	}
+
+	commands := make([]string, 0, len(t.byCommand))
+	for command := range t.byCommand {
+		commands = append(commands, command)
+	}
+	sort.Strings(commands)
+	for _, command := range commands {
+		fmt.Fprintf(&b, "  %-40s %s\n", "command "+command, t.format(*t.byCommand[command]))
+	}
+
+	fmt.Fprintf(&b, "  %-40s %s\n", "run total", t.format(t.run))
+	if t.budget > 0 {
+		status := "within"
+		if t.exceeded() {
+			status = "exceeded"
+		}
+		if t.Period > 0 {
+			fmt.Fprintf(&b, "  Budget: $%.4f per %s, $%.4f spent (%s)\n", t.budget, t.Period, t.spent.Cost, status)
+		} else {
+			fmt.Fprintf(&b, "  Budget: $%.4f (%s)\n", t.budget, status)
+		}
+	}
+	return b.String()
+}
+
+func (t *Tracker) format(totals Totals) string {
+	line := fmt.Sprintf("%4d calls  %8d in  %7d out", totals.Calls, totals.InputTokens, totals.OutputTokens)
+	if t.priced {
+		line += fmt.Sprintf("  $%.4f", totals.Cost)
+	}
+	return line
+}
+
+// Context returns the command being run and the files whose data is being sent
+type Context func(ctx context.Context) (command string, files []string)
+
+// LLMService wraps an LLMService and records the usage of every call with a Tracker
+type LLMService struct {
+	next    interfaces.LLMService
+	tracker *Tracker
+	context Context
+}
+
+// NewLLMService creates a new instance of LLMService
+func NewLLMService(next interfaces.LLMService, tracker *Tracker, context Context) interfaces.LLMService {
+	return &LLMService{next: next, tracker: tracker, context: context}
+}
+
+// GenerateText implements the LLMService interface
+func (s *LLMService) GenerateText(prompt string) (string, error) {
+	text, _, err := s.GenerateTextWithUsage(context.Background(), prompt)
+	return text, err
+}
+
+// GenerateTextWithUsage implements the UsageLLMService interface. The call that uses up
+// the budget still returns its response, but no further calls are made.
+func (s *LLMService) GenerateTextWithUsage(ctx context.Context, prompt string) (string, interfaces.TokenUsage, error) {
+	if err := s.tracker.Check(); err != nil {
+		return "", interfaces.TokenUsage{}, err
+	}
+
+	text, u, err := interfaces.GenerateTextWithUsage(ctx, s.next, prompt)
+	command, files := s.context(ctx)
+	s.tracker.Record(command, files, u)
+	if err == nil && u == (interfaces.TokenUsage{}) && s.tracker.Priced() && s.tracker.OnNoUsage != nil {
+		s.tracker.OnNoUsage()
+	}
+	return text, u, err
+}
@@ -0,0 +1,67 @@
+package links
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+)
+
+// ExtractLinks returns the target URIs of all link annotations in a PDF, in page order and without duplicates.
+// OCR only sees the visible text, so links behind words like "LinkedIn" or "Portfolio" are only
+// available from the annotations.
+func ExtractLinks(pdfPath string) ([]string, error) {
+	f, err := os.Open(pdfPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open PDF: %w", err)
+	}
+	defer f.Close()
+
+	annotations, err := api.Annotations(f, nil, model.NewDefaultConfiguration())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read annotations: %w", err)
+	}
+
+	pageCount := 0
+	for page := range annotations {
+		pageCount = max(pageCount, page)
+	}
+
+	var uris []string
+	seen := map[string]bool{}
+	for page := 1; page <= pageCount; page++ {
+		pageAnnotations, ok := annotations[page]
+		if !ok {
+			continue
+		}
+		linkAnnotations, ok := pageAnnotations[model.AnnLink]
+		if !ok {
+			continue
+		}
+		objNrs := make([]int, 0, len(linkAnnotations.Map))
+		for objNr := range linkAnnotations.Map {
+			objNrs = append(objNrs, objNr)
+		}
+		sort.Ints(objNrs)
+
+		for _, objNr := range objNrs {
+			var uri string
+			switch link := linkAnnotations.Map[objNr].(type) {
+			case model.LinkAnnotation:
+				uri = link.URI
+			case *model.LinkAnnotation:
+				uri = link.URI
+			}
+			uri = strings.TrimSpace(uri)
+			if uri == "" || seen[uri] {
+				continue
+			}
+			seen[uri] = true
+			uris = append(uris, uri)
+		}
+	}
+	return uris, nil
+}
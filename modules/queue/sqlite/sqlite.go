@@ -0,0 +1,419 @@
+package sqlite
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nicoalimin/resume-analyzer/interfaces"
+	"github.com/nicoalimin/resume-analyzer/modules/crypto/envelope"
+	_ "modernc.org/sqlite"
+)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS jobs (
+	id          TEXT PRIMARY KEY,
+	team        TEXT NOT NULL DEFAULT '',
+	created_at  TEXT NOT NULL,
+	canceled_at TEXT,
+	notified_at TEXT
+);
+
+CREATE TABLE IF NOT EXISTS tasks (
+	id           INTEGER PRIMARY KEY AUTOINCREMENT,
+	job_id       TEXT NOT NULL REFERENCES jobs(id) ON DELETE CASCADE,
+	name         TEXT NOT NULL,
+	content      BLOB,
+	state        TEXT NOT NULL,
+	attempts     INTEGER NOT NULL DEFAULT 0,
+	error        TEXT NOT NULL DEFAULT '',
+	result       BLOB,
+	available_at TEXT NOT NULL,
+	updated_at   TEXT NOT NULL,
+	finished_at  TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_tasks_job ON tasks(job_id);
+CREATE INDEX IF NOT EXISTS idx_tasks_due ON tasks(state, available_at, id);
+`
+
+// columnMigrations adds columns introduced after a table was first created to existing databases
+var columnMigrations = []struct {
+	table      string
+	column     string
+	definition string
+}{
+	{"jobs", "notified_at", "TEXT"},
+	{"jobs", "team", "TEXT NOT NULL DEFAULT ''"},
+}
+
+// SQLiteQueue implements the JobQueue interface using a local SQLite database. Uploaded files
+// are kept until their task is finished. With a cipher, files and results are encrypted.
+type SQLiteQueue struct {
+	db     *sql.DB
+	cipher *envelope.Cipher
+}
+
+// NewSQLiteQueue opens (and if needed creates) the SQLite job queue at path. cipher may be nil.
+func NewSQLiteQueue(path string, cipher *envelope.Cipher) (interfaces.JobQueue, error) {
+	db, err := sql.Open("sqlite", path+"?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_pragma=secure_delete(1)")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open job queue: %w", err)
+	}
+
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create schema: %w", err)
+	}
+	if err := migrateColumns(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &SQLiteQueue{db: db, cipher: cipher}, nil
+}
+
+// Submit implements the JobQueue interface
+func (q *SQLiteQueue) Submit(team string, files []interfaces.JobUpload) (interfaces.Job, error) {
+	id, err := newJobID()
+	if err != nil {
+		return interfaces.Job{}, err
+	}
+
+	tx, err := q.db.Begin()
+	if err != nil {
+		return interfaces.Job{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	created := now()
+	if _, err := tx.Exec(`INSERT INTO jobs (id, team, created_at) VALUES (?, ?, ?)`, id, team, created); err != nil {
+		return interfaces.Job{}, fmt.Errorf("failed to create job: %w", err)
+	}
+	for _, file := range files {
+		content, err := q.seal(file.Content)
+		if err != nil {
+			return interfaces.Job{}, err
+		}
+		_, err = tx.Exec(
+			`INSERT INTO tasks (job_id, name, content, state, available_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)`,
+			id, file.Name, content, interfaces.JobQueued, created, created,
+		)
+		if err != nil {
+			return interfaces.Job{}, fmt.Errorf("failed to queue %s: %w", file.Name, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return interfaces.Job{}, fmt.Errorf("failed to commit job: %w", err)
+	}
+	return q.Get(team, id)
+}
+
+// Get implements the JobQueue interface
+func (q *SQLiteQueue) Get(team string, id string) (interfaces.Job, error) {
+	job := interfaces.Job{ID: id, Files: []interfaces.JobFile{}}
+	var created string
+	var canceled sql.NullString
+	err := q.db.QueryRow(
+		`SELECT team, created_at, canceled_at FROM jobs WHERE id = ? AND (? = '' OR team = ?)`, id, team, team,
+	).Scan(&job.Team, &created, &canceled)
+	if errors.Is(err, sql.ErrNoRows) {
+		return interfaces.Job{}, interfaces.ErrJobNotFound
+	}
+	if err != nil {
+		return interfaces.Job{}, fmt.Errorf("failed to read job: %w", err)
+	}
+
+	job.Created, _ = time.Parse(time.RFC3339, created)
+
+	rows, err := q.db.Query(`SELECT id, name, state, attempts, error, result, finished_at FROM tasks WHERE job_id = ? ORDER BY id`, id)
+	if err != nil {
+		return interfaces.Job{}, fmt.Errorf("failed to read job files: %w", err)
+	}
+	defer rows.Close()
+
+	var lastFinished string
+	for rows.Next() {
+		var file interfaces.JobFile
+		var state string
+		var result []byte
+		var finished sql.NullString
+		if err := rows.Scan(&file.TaskID, &file.Name, &state, &file.Attempts, &file.Error, &result, &finished); err != nil {
+			return interfaces.Job{}, fmt.Errorf("failed to read job file: %w", err)
+		}
+		file.State = interfaces.JobState(state)
+		if result != nil {
+			if file.Result, err = q.open(result); err != nil {
+				return interfaces.Job{}, err
+			}
+		}
+		if finished.String > lastFinished {
+			lastFinished = finished.String
+		}
+		job.Files = append(job.Files, file)
+	}
+	if err := rows.Err(); err != nil {
+		return interfaces.Job{}, fmt.Errorf("failed to read job files: %w", err)
+	}
+
+	job.State = jobState(job.Files, canceled.Valid)
+	if job.State != interfaces.JobQueued && job.State != interfaces.JobRunning && lastFinished != "" {
+		finished, _ := time.Parse(time.RFC3339, lastFinished)
+		job.Finished = &finished
+	}
+	return job, nil
+}
+
+// List implements the JobQueue interface
+func (q *SQLiteQueue) List(team string) ([]interfaces.Job, error) {
+	rows, err := q.db.Query(`SELECT id FROM jobs WHERE ? = '' OR team = ? ORDER BY created_at DESC, rowid DESC`, team, team)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list jobs: %w", err)
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to list jobs: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list jobs: %w", err)
+	}
+
+	jobs := make([]interfaces.Job, 0, len(ids))
+	for _, id := range ids {
+		job, err := q.Get(team, id)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+// Cancel implements the JobQueue interface
+func (q *SQLiteQueue) Cancel(team string, id string) (interfaces.Job, error) {
+	tx, err := q.db.Begin()
+	if err != nil {
+		return interfaces.Job{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var exists int
+	err = tx.QueryRow(`SELECT COUNT(*) FROM jobs WHERE id = ? AND (? = '' OR team = ?)`, id, team, team).Scan(&exists)
+	if err != nil {
+		return interfaces.Job{}, fmt.Errorf("failed to read job: %w", err)
+	}
+	if exists == 0 {
+		return interfaces.Job{}, interfaces.ErrJobNotFound
+	}
+
+	timestamp := now()
+	result, err := tx.Exec(
+		`UPDATE tasks SET state = ?, content = NULL, updated_at = ?, finished_at = ? WHERE job_id = ? AND state IN (?, ?)`,
+		interfaces.JobCanceled, timestamp, timestamp, id, interfaces.JobQueued, interfaces.JobRunning,
+	)
+	if err != nil {
+		return interfaces.Job{}, fmt.Errorf("failed to cancel job: %w", err)
+	}
+	// A job that had already finished keeps its state
+	if canceled, _ := result.RowsAffected(); canceled > 0 {
+		if _, err := tx.Exec(`UPDATE jobs SET canceled_at = ? WHERE id = ?`, timestamp, id); err != nil {
+			return interfaces.Job{}, fmt.Errorf("failed to cancel job: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return interfaces.Job{}, fmt.Errorf("failed to commit cancellation: %w", err)
+	}
+	return q.Get(team, id)
+}
+
+// Claim implements the JobQueue interface
+func (q *SQLiteQueue) Claim() (*interfaces.JobTask, error) {
+	timestamp := now()
+	var task interfaces.JobTask
+	var content []byte
+	err := q.db.QueryRow(
+		`UPDATE tasks SET state = ?, attempts = attempts + 1, updated_at = ?
+		WHERE id = (SELECT id FROM tasks WHERE state = ? AND available_at <= ? ORDER BY id LIMIT 1)
+		RETURNING id, job_id, (SELECT team FROM jobs WHERE jobs.id = tasks.job_id), name, content, attempts`,
+		interfaces.JobRunning, timestamp, interfaces.JobQueued, timestamp,
+	).Scan(&task.ID, &task.JobID, &task.Team, &task.Name, &content, &task.Attempts)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim task: %w", err)
+	}
+
+	task.Content, err = q.open(content)
+	if err != nil {
+		// The file can never be analyzed, so the task is not retried
+		q.Fail(task.ID, err.Error())
+		return nil, err
+	}
+	return &task, nil
+}
+
+// Complete implements the JobQueue interface
+func (q *SQLiteQueue) Complete(taskID int64, result []byte) error {
+	sealed, err := q.seal(result)
+	if err != nil {
+		return err
+	}
+	timestamp := now()
+	_, err = q.db.Exec(
+		`UPDATE tasks SET state = ?, result = ?, error = '', content = NULL, updated_at = ?, finished_at = ? WHERE id = ? AND state = ?`,
+		interfaces.JobSucceeded, sealed, timestamp, timestamp, taskID, interfaces.JobRunning,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to complete task: %w", err)
+	}
+	return nil
+}
+
+// Retry implements the JobQueue interface
+func (q *SQLiteQueue) Retry(taskID int64, reason string, at time.Time) error {
+	_, err := q.db.Exec(
+		`UPDATE tasks SET state = ?, error = ?, available_at = ?, updated_at = ? WHERE id = ? AND state = ?`,
+		interfaces.JobQueued, reason, at.UTC().Format(time.RFC3339), now(), taskID, interfaces.JobRunning,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to retry task: %w", err)
+	}
+	return nil
+}
+
+// Fail implements the JobQueue interface
+func (q *SQLiteQueue) Fail(taskID int64, reason string) error {
+	timestamp := now()
+	_, err := q.db.Exec(
+		`UPDATE tasks SET state = ?, error = ?, content = NULL, updated_at = ?, finished_at = ? WHERE id = ? AND state = ?`,
+		interfaces.JobFailed, reason, timestamp, timestamp, taskID, interfaces.JobRunning,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to fail task: %w", err)
+	}
+	return nil
+}
+
+// ClaimFinished implements the JobQueue interface
+func (q *SQLiteQueue) ClaimFinished(id string) (*interfaces.Job, error) {
+	job, err := q.Get("", id)
+	if err != nil {
+		return nil, err
+	}
+	if job.State == interfaces.JobQueued || job.State == interfaces.JobRunning {
+		return nil, nil
+	}
+
+	result, err := q.db.Exec(`UPDATE jobs SET notified_at = ? WHERE id = ? AND notified_at IS NULL`, now(), id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to mark job as reported: %w", err)
+	}
+	if claimed, _ := result.RowsAffected(); claimed == 0 {
+		return nil, nil
+	}
+	return &job, nil
+}
+
+// Requeue implements the JobQueue interface
+func (q *SQLiteQueue) Requeue() (int, error) {
+	result, err := q.db.Exec(`UPDATE tasks SET state = ?, updated_at = ? WHERE state = ?`, interfaces.JobQueued, now(), interfaces.JobRunning)
+	if err != nil {
+		return 0, fmt.Errorf("failed to requeue tasks: %w", err)
+	}
+	requeued, err := result.RowsAffected()
+	return int(requeued), err
+}
+
+// Close implements the JobQueue interface
+func (q *SQLiteQueue) Close() error {
+	return q.db.Close()
+}
+
+func migrateColumns(db *sql.DB) error {
+	for _, migration := range columnMigrations {
+		var count int
+		err := db.QueryRow(`SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`, migration.table, migration.column).Scan(&count)
+		if err != nil {
+			return fmt.Errorf("failed to inspect table %s: %w", migration.table, err)
+		}
+		if count > 0 {
+			continue
+		}
+		_, err = db.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, migration.table, migration.column, migration.definition))
+		if err != nil {
+			return fmt.Errorf("failed to add column %s.%s: %w", migration.table, migration.column, err)
+		}
+	}
+	return nil
+}
+
+// jobState derives the state of a job from the states of its files
+func jobState(files []interfaces.JobFile, canceled bool) interfaces.JobState {
+	started, unfinished, succeeded := false, false, false
+	for _, file := range files {
+		switch file.State {
+		case interfaces.JobQueued:
+			unfinished = true
+			started = started || file.Attempts > 0
+		case interfaces.JobRunning:
+			unfinished, started = true, true
+		case interfaces.JobSucceeded:
+			started, succeeded = true, true
+		default:
+			started = true
+		}
+	}
+	switch {
+	case unfinished && started:
+		return interfaces.JobRunning
+	case unfinished:
+		return interfaces.JobQueued
+	case canceled:
+		return interfaces.JobCanceled
+	case succeeded:
+		return interfaces.JobSucceeded
+	default:
+		return interfaces.JobFailed
+	}
+}
+
+// seal encrypts data if a cipher is configured
+func (q *SQLiteQueue) seal(data []byte) ([]byte, error) {
+	if q.cipher == nil {
+		return data, nil
+	}
+	return q.cipher.Encrypt(data)
+}
+
+// open decrypts data written with a cipher
+func (q *SQLiteQueue) open(data []byte) ([]byte, error) {
+	if !envelope.IsEncrypted(data) {
+		return data, nil
+	}
+	if q.cipher == nil {
+		return nil, envelope.ErrEncrypted
+	}
+	return q.cipher.Decrypt(data)
+}
+
+func newJobID() (string, error) {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return "", fmt.Errorf("failed to generate job ID: %w", err)
+	}
+	return hex.EncodeToString(id), nil
+}
+
+func now() string {
+	return time.Now().UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,274 @@
+package replay
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/nicoalimin/resume-analyzer/interfaces"
+)
+
+// ErrNoFixture is returned when no response was recorded for a prompt or document
+var ErrNoFixture = errors.New("no recorded response")
+
+// LLMFixture is a recorded LLM call, stored in <dir>/llm/<prompt sha256>.json
+type LLMFixture struct {
+	Candidates []string              `json:"candidates,omitempty"`
+	Prompt     string                `json:"prompt"`
+	Response   string                `json:"response"`
+	Usage      interfaces.TokenUsage `json:"usage"`
+	Error      string                `json:"error,omitempty"`
+}
+
+// OCRFixture is a recorded OCR call, stored in <dir>/ocr/<PDF sha256>.json
+type OCRFixture struct {
+	Source string `json:"source"`
+	Text   string `json:"text"`
+	Error  string `json:"error,omitempty"`
+}
+
+// LLMRecorder wraps an LLMService and saves every call as a fixture
+type LLMRecorder struct {
+	next interfaces.LLMService
+	dir  string
+	mu   sync.Mutex
+}
+
+// NewLLMRecorder creates a new instance of LLMRecorder that records to dir
+func NewLLMRecorder(next interfaces.LLMService, dir string) interfaces.LLMService {
+	return &LLMRecorder{next: next, dir: dir}
+}
+
+// GenerateText implements the LLMService interface
+func (r *LLMRecorder) GenerateText(prompt string) (string, error) {
+	text, _, err := r.GenerateTextWithUsage(context.Background(), prompt)
+	return text, err
+}
+
+// GenerateTextWithUsage implements the UsageLLMService interface
+func (r *LLMRecorder) GenerateTextWithUsage(ctx context.Context, prompt string) (string, interfaces.TokenUsage, error) {
+	text, usage, err := interfaces.GenerateTextWithUsage(ctx, r.next, prompt)
+
+	fixture := LLMFixture{Candidates: interfaces.CallFrom(ctx).Candidates, Prompt: prompt, Response: text, Usage: usage}
+	if err != nil {
+		fixture.Error = err.Error()
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if writeErr := writeFixture(filepath.Join(r.dir, "llm", hash([]byte(prompt))+".json"), fixture); writeErr != nil {
+		return "", usage, writeErr
+	}
+	return text, usage, err
+}
+
+// LLMPlayer implements the LLMService interface by serving recorded fixtures. Prompts
+// that were not recorded fail with ErrNoFixture.
+type LLMPlayer struct {
+	dir string
+}
+
+// NewLLMPlayer creates a new instance of LLMPlayer that replays the fixtures in dir
+func NewLLMPlayer(dir string) interfaces.LLMService {
+	return &LLMPlayer{dir: dir}
+}
+
+// GenerateText implements the LLMService interface
+func (p *LLMPlayer) GenerateText(prompt string) (string, error) {
+	text, _, err := p.GenerateTextWithUsage(context.Background(), prompt)
+	return text, err
+}
+
+// GenerateTextWithUsage implements the UsageLLMService interface
+func (p *LLMPlayer) GenerateTextWithUsage(ctx context.Context, prompt string) (string, interfaces.TokenUsage, error) {
+	key := hash([]byte(prompt))
+	var fixture LLMFixture
+	if err := readFixture(filepath.Join(p.dir, "llm", key+".json"), &fixture); err != nil {
+		return "", interfaces.TokenUsage{}, fmt.Errorf("prompt %s (%s): %w", key[:12], preview(prompt), err)
+	}
+	if fixture.Error != "" {
+		return fixture.Response, fixture.Usage, errors.New(fixture.Error)
+	}
+	return fixture.Response, fixture.Usage, nil
+}
+
+// OCRRecorder wraps an OCRService and saves every call as a fixture
+type OCRRecorder struct {
+	next interfaces.OCRService
+	dir  string
+	mu   sync.Mutex
+}
+
+// NewOCRRecorder creates a new instance of OCRRecorder that records to dir
+func NewOCRRecorder(next interfaces.OCRService, dir string) interfaces.OCRService {
+	return &OCRRecorder{next: next, dir: dir}
+}
+
+// ExtractTextFromPDF implements the OCRService interface
+func (r *OCRRecorder) ExtractTextFromPDF(pdfPath string) (string, error) {
+	key, err := hashFile(pdfPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", pdfPath, err)
+	}
+	text, err := r.next.ExtractTextFromPDF(pdfPath)
+
+	fixture := OCRFixture{Source: filepath.Base(pdfPath), Text: text}
+	if err != nil {
+		fixture.Error = err.Error()
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if writeErr := writeFixture(filepath.Join(r.dir, "ocr", key+".json"), fixture); writeErr != nil {
+		return "", writeErr
+	}
+	return text, err
+}
+
+// OCRPlayer implements the OCRService interface by serving recorded fixtures, matched by
+// the content of the PDF rather than its name
+type OCRPlayer struct {
+	dir string
+}
+
+// NewOCRPlayer creates a new instance of OCRPlayer that replays the fixtures in dir
+func NewOCRPlayer(dir string) interfaces.OCRService {
+	return &OCRPlayer{dir: dir}
+}
+
+// ExtractTextFromPDF implements the OCRService interface
+func (p *OCRPlayer) ExtractTextFromPDF(pdfPath string) (string, error) {
+	key, err := hashFile(pdfPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", pdfPath, err)
+	}
+	var fixture OCRFixture
+	if err := readFixture(filepath.Join(p.dir, "ocr", key+".json"), &fixture); err != nil {
+		return "", fmt.Errorf("%s: %w", filepath.Base(pdfPath), err)
+	}
+	if fixture.Error != "" {
+		return fixture.Text, errors.New(fixture.Error)
+	}
+	return fixture.Text, nil
+}
+
+// Forget removes every fixture in dir that holds the data of one of the candidates and returns
+// how many were removed. LLM fixtures are matched by the candidates they were recorded for,
+// OCR fixtures by the name of their PDF, and both by any of mentions (e.g. names and email
+// addresses) appearing in their text, which also finds fixtures recorded without candidates.
+func Forget(dir string, candidates []string, mentions []string) (int, error) {
+	forget := map[string]bool{}
+	for _, candidate := range candidates {
+		forget[candidate] = true
+	}
+	mentioned := func(texts ...string) bool {
+		for _, text := range texts {
+			text = strings.ToLower(text)
+			for _, mention := range mentions {
+				if mention != "" && strings.Contains(text, strings.ToLower(mention)) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	removed := 0
+	for _, kind := range []string{"llm", "ocr"} {
+		paths, err := filepath.Glob(filepath.Join(dir, kind, "*.json"))
+		if err != nil {
+			return removed, err
+		}
+		for _, path := range paths {
+			matches := false
+			if kind == "llm" {
+				var fixture LLMFixture
+				if err := readFixture(path, &fixture); err != nil {
+					return removed, err
+				}
+				matches = mentioned(fixture.Prompt, fixture.Response)
+				for _, candidate := range fixture.Candidates {
+					matches = matches || forget[candidate]
+				}
+			} else {
+				var fixture OCRFixture
+				if err := readFixture(path, &fixture); err != nil {
+					return removed, err
+				}
+				source := filepath.Base(fixture.Source)
+				matches = forget[strings.TrimSuffix(source, filepath.Ext(source))] || mentioned(fixture.Text)
+			}
+			if !matches {
+				continue
+			}
+			if err := os.Remove(path); err != nil {
+				return removed, err
+			}
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// writeFixture saves a fixture as indented JSON, so recordings can be reviewed in diffs
+func writeFixture(path string, fixture interface{}) error {
+	data, err := json.MarshalIndent(fixture, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode fixture: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create fixture folder: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write fixture: %w", err)
+	}
+	return nil
+}
+
+func readFixture(path string, fixture interface{}) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrNoFixture
+	}
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, fixture); err != nil {
+		return fmt.Errorf("invalid fixture %s: %w", path, err)
+	}
+	return nil
+}
+
+// preview returns the start of a prompt for error messages
+func preview(prompt string) string {
+	const length = 60
+	runes := []rune(prompt)
+	if len(runes) > length {
+		return string(runes[:length]) + "..."
+	}
+	return string(runes)
+}
+
+func hash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,651 @@
+package sqlite
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nicoalimin/resume-analyzer/interfaces"
+	"github.com/nicoalimin/resume-analyzer/modules/crypto/envelope"
+	_ "modernc.org/sqlite"
+)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS runs (
+	id          INTEGER PRIMARY KEY AUTOINCREMENT,
+	command     TEXT NOT NULL,
+	campaign    TEXT NOT NULL,
+	args        TEXT NOT NULL,
+	status      TEXT NOT NULL,
+	started_at  TEXT NOT NULL,
+	finished_at TEXT
+);
+
+CREATE TABLE IF NOT EXISTS candidates (
+	id         INTEGER PRIMARY KEY AUTOINCREMENT,
+	campaign   TEXT NOT NULL,
+	key        TEXT NOT NULL,
+	name       TEXT NOT NULL DEFAULT '',
+	created_at TEXT NOT NULL,
+	updated_at TEXT NOT NULL,
+	UNIQUE (campaign, key)
+);
+
+CREATE TABLE IF NOT EXISTS documents (
+	id           INTEGER PRIMARY KEY AUTOINCREMENT,
+	candidate_id INTEGER NOT NULL REFERENCES candidates(id) ON DELETE CASCADE,
+	run_id       INTEGER REFERENCES runs(id),
+	path         TEXT NOT NULL,
+	sha256       TEXT NOT NULL,
+	ocr_text     TEXT NOT NULL,
+	links        TEXT NOT NULL DEFAULT '[]',
+	source_key   TEXT NOT NULL DEFAULT '',
+	created_at   TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS summaries (
+	id           INTEGER PRIMARY KEY AUTOINCREMENT,
+	candidate_id INTEGER NOT NULL REFERENCES candidates(id) ON DELETE CASCADE,
+	run_id       INTEGER REFERENCES runs(id),
+	summary      TEXT NOT NULL,
+	source_key   TEXT NOT NULL DEFAULT '',
+	created_at   TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS extractions (
+	id               INTEGER PRIMARY KEY AUTOINCREMENT,
+	candidate_id     INTEGER NOT NULL REFERENCES candidates(id) ON DELETE CASCADE,
+	run_id           INTEGER REFERENCES runs(id),
+	name             TEXT NOT NULL,
+	role             TEXT NOT NULL,
+	seniority        TEXT NOT NULL,
+	status           TEXT NOT NULL,
+	current_position TEXT NOT NULL,
+	current_company  TEXT NOT NULL,
+	years_of_exp     TEXT NOT NULL,
+	cv_link          TEXT NOT NULL,
+	skillset         TEXT NOT NULL,
+	remarks          TEXT NOT NULL,
+	email            TEXT NOT NULL DEFAULT '',
+	phone            TEXT NOT NULL DEFAULT '',
+	location         TEXT NOT NULL DEFAULT '',
+	linkedin         TEXT NOT NULL DEFAULT '',
+	github           TEXT NOT NULL DEFAULT '',
+	portfolio        TEXT NOT NULL DEFAULT '',
+	unverified       TEXT NOT NULL,
+	source_key       TEXT NOT NULL DEFAULT '',
+	created_at       TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS scores (
+	id           INTEGER PRIMARY KEY AUTOINCREMENT,
+	candidate_id INTEGER NOT NULL REFERENCES candidates(id) ON DELETE CASCADE,
+	run_id       INTEGER REFERENCES runs(id),
+	name         TEXT NOT NULL,
+	value        REAL NOT NULL,
+	source_key   TEXT NOT NULL DEFAULT '',
+	created_at   TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS candidate_aliases (
+	campaign     TEXT NOT NULL,
+	key          TEXT NOT NULL,
+	candidate_id INTEGER NOT NULL REFERENCES candidates(id) ON DELETE CASCADE,
+	created_at   TEXT NOT NULL,
+	PRIMARY KEY (campaign, key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_documents_candidate ON documents(candidate_id);
+CREATE INDEX IF NOT EXISTS idx_summaries_candidate ON summaries(candidate_id);
+CREATE INDEX IF NOT EXISTS idx_extractions_candidate ON extractions(candidate_id);
+CREATE INDEX IF NOT EXISTS idx_scores_candidate ON scores(candidate_id);
+`
+
+// columnMigrations adds columns introduced after a table was first created to existing databases
+var columnMigrations = []struct {
+	table      string
+	column     string
+	definition string
+}{
+	{"extractions", "email", "TEXT NOT NULL DEFAULT ''"},
+	{"extractions", "phone", "TEXT NOT NULL DEFAULT ''"},
+	{"extractions", "location", "TEXT NOT NULL DEFAULT ''"},
+	{"extractions", "linkedin", "TEXT NOT NULL DEFAULT ''"},
+	{"extractions", "github", "TEXT NOT NULL DEFAULT ''"},
+	{"extractions", "portfolio", "TEXT NOT NULL DEFAULT ''"},
+	{"documents", "links", "TEXT NOT NULL DEFAULT '[]'"},
+	{"documents", "source_key", "TEXT NOT NULL DEFAULT ''"},
+	{"summaries", "source_key", "TEXT NOT NULL DEFAULT ''"},
+	{"extractions", "source_key", "TEXT NOT NULL DEFAULT ''"},
+	{"scores", "source_key", "TEXT NOT NULL DEFAULT ''"},
+}
+
+// resultTables hold the documents and results of a candidate. Their source_key is the key the
+// row was stored under, so the rows of a merged duplicate can still be told apart; an empty
+// source_key is the candidate's own key.
+var resultTables = []string{"documents", "summaries", "extractions", "scores"}
+
+// SQLiteStore implements the CandidateStore interface using a local SQLite database.
+// With a cipher, the candidate data (names, OCR text, summaries and extracted fields) is
+// encrypted; candidate keys, hashes, scores and timestamps are stored as they are.
+type SQLiteStore struct {
+	db     *sql.DB
+	cipher *envelope.Cipher
+}
+
+// NewSQLiteStore opens (and if needed creates) the SQLite database at path. The cipher is
+// optional; rows written without one can still be read after encryption is turned on.
+func NewSQLiteStore(path string, cipher *envelope.Cipher) (interfaces.CandidateStore, error) {
+	db, err := sql.Open("sqlite", path+"?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_pragma=secure_delete(1)")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create schema: %w", err)
+	}
+	if err := migrateColumns(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &SQLiteStore{db: db, cipher: cipher}, nil
+}
+
+// StartRun implements the CandidateStore interface
+func (s *SQLiteStore) StartRun(command string, campaign string, args []string) (int64, error) {
+	encodedArgs, err := json.Marshal(args)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode run args: %w", err)
+	}
+
+	result, err := s.db.Exec(
+		`INSERT INTO runs (command, campaign, args, status, started_at) VALUES (?, ?, ?, 'running', ?)`,
+		command, campaign, string(encodedArgs), now(),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to record run: %w", err)
+	}
+	return result.LastInsertId()
+}
+
+// FinishRun implements the CandidateStore interface
+func (s *SQLiteStore) FinishRun(runID int64, status string) error {
+	_, err := s.db.Exec(`UPDATE runs SET status = ?, finished_at = ? WHERE id = ?`, status, now(), runID)
+	if err != nil {
+		return fmt.Errorf("failed to finish run: %w", err)
+	}
+	return nil
+}
+
+// SaveDocument implements the CandidateStore interface
+func (s *SQLiteStore) SaveDocument(runID int64, candidate interfaces.CandidateRef, doc interfaces.Document) error {
+	candidateID, err := s.upsertCandidate(candidate, "")
+	if err != nil {
+		return err
+	}
+
+	links, err := json.Marshal(doc.Links)
+	if err != nil {
+		return fmt.Errorf("failed to encode links: %w", err)
+	}
+
+	sealed, err := s.seal(doc.Path, doc.OCRText, string(links))
+	if err != nil {
+		return err
+	}
+
+	// A rerun on the same file updates its document instead of adding another one
+	result, err := s.db.Exec(
+		`UPDATE documents SET run_id = ?, path = ?, ocr_text = ?, links = ? WHERE candidate_id = ? AND sha256 = ?`,
+		nullableRunID(runID), sealed[0], sealed[1], sealed[2], candidateID, doc.SHA256,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save document: %w", err)
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to save document: %w", err)
+	}
+	if updated > 0 {
+		return nil
+	}
+	_, err = s.db.Exec(
+		`INSERT INTO documents (candidate_id, run_id, path, sha256, ocr_text, links, source_key, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		candidateID, nullableRunID(runID), sealed[0], doc.SHA256, sealed[1], sealed[2], candidate.Key, now(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save document: %w", err)
+	}
+	return nil
+}
+
+// SaveSummary implements the CandidateStore interface
+func (s *SQLiteStore) SaveSummary(runID int64, candidate interfaces.CandidateRef, summary string) error {
+	candidateID, err := s.upsertCandidate(candidate, "")
+	if err != nil {
+		return err
+	}
+
+	sealed, err := s.seal(summary)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(
+		`INSERT INTO summaries (candidate_id, run_id, summary, source_key, created_at) VALUES (?, ?, ?, ?, ?)`,
+		candidateID, nullableRunID(runID), sealed[0], candidate.Key, now(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save summary: %w", err)
+	}
+	return nil
+}
+
+// SaveExtraction implements the CandidateStore interface
+func (s *SQLiteStore) SaveExtraction(runID int64, candidate interfaces.CandidateRef, applicant interfaces.ApplicantInfo) error {
+	candidateID, err := s.upsertCandidate(candidate, applicant.Name)
+	if err != nil {
+		return err
+	}
+
+	unverified, err := json.Marshal(applicant.Unverified)
+	if err != nil {
+		return fmt.Errorf("failed to encode unverified values: %w", err)
+	}
+
+	sealed, err := s.seal(applicant.Name, applicant.Role, applicant.Seniority, applicant.Status,
+		applicant.CurrentPosition, applicant.CurrentCompany, applicant.YearsOfExp, applicant.CVLink,
+		applicant.Skillset, applicant.Remarks, applicant.Email, applicant.Phone, applicant.Location,
+		applicant.LinkedIn, applicant.GitHub, applicant.Portfolio, string(unverified))
+	if err != nil {
+		return err
+	}
+	args := append([]any{candidateID, nullableRunID(runID)}, sealed...)
+	_, err = s.db.Exec(
+		`INSERT INTO extractions (
+			candidate_id, run_id, name, role, seniority, status, current_position, current_company,
+			years_of_exp, cv_link, skillset, remarks, email, phone, location, linkedin, github,
+			portfolio, unverified, source_key, created_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		append(args, candidate.Key, now())...,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save extraction: %w", err)
+	}
+	return nil
+}
+
+// SaveScore implements the CandidateStore interface
+func (s *SQLiteStore) SaveScore(runID int64, candidate interfaces.CandidateRef, name string, value float64) error {
+	candidateID, err := s.upsertCandidate(candidate, "")
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(
+		`INSERT INTO scores (candidate_id, run_id, name, value, source_key, created_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		candidateID, nullableRunID(runID), name, value, candidate.Key, now(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save score: %w", err)
+	}
+	return nil
+}
+
+// ListCandidates implements the CandidateStore interface
+func (s *SQLiteStore) ListCandidates(campaign string) ([]interfaces.StoredCandidate, error) {
+	rows, err := s.db.Query(`
+		SELECT c.id, c.campaign, c.key, c.name, c.created_at, c.updated_at,
+			(SELECT COUNT(*) FROM documents d WHERE d.candidate_id = c.id),
+			COALESCE(d.path, ''), COALESCE(d.sha256, ''), COALESCE(d.ocr_text, ''), COALESCE(d.links, '[]'),
+			COALESCE((SELECT m.summary FROM summaries m WHERE m.candidate_id = c.id ORDER BY m.id DESC LIMIT 1), '')
+		FROM candidates c
+		LEFT JOIN documents d ON d.id = (SELECT MAX(id) FROM documents WHERE candidate_id = c.id)
+		WHERE ? = '' OR c.campaign = ?
+		ORDER BY c.campaign, c.key`, campaign, campaign)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list candidates: %w", err)
+	}
+	defer rows.Close()
+
+	var candidates []interfaces.StoredCandidate
+	for rows.Next() {
+		var candidate interfaces.StoredCandidate
+		var createdAt, updatedAt, links string
+		err := rows.Scan(&candidate.ID, &candidate.Campaign, &candidate.Key, &candidate.Name, &createdAt, &updatedAt,
+			&candidate.Documents, &candidate.Document.Path, &candidate.Document.SHA256,
+			&candidate.Document.OCRText, &links, &candidate.Summary)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read candidate: %w", err)
+		}
+		err = s.open(&candidate.Name, &candidate.Document.Path, &candidate.Document.OCRText, &links, &candidate.Summary)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(links), &candidate.Document.Links); err != nil {
+			return nil, fmt.Errorf("failed to decode links: %w", err)
+		}
+		candidate.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+		candidate.UpdatedAt, _ = time.Parse(time.RFC3339, updatedAt)
+		candidates = append(candidates, candidate)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list candidates: %w", err)
+	}
+
+	for i := range candidates {
+		extraction, err := s.latestExtraction(candidates[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		candidates[i].Extraction = extraction
+	}
+	return candidates, nil
+}
+
+// MergeCandidates implements the CandidateStore interface
+func (s *SQLiteStore) MergeCandidates(targetID int64, sourceID int64) error {
+	if targetID == sourceID {
+		return fmt.Errorf("cannot merge candidate %d into itself", targetID)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to start merge: %w", err)
+	}
+	defer tx.Rollback()
+
+	timestamp := now()
+	type statement struct {
+		query string
+		args  []any
+	}
+	statements := []statement{
+		// Remember the source's campaign and key, and any aliases it already had
+		{`INSERT OR REPLACE INTO candidate_aliases (campaign, key, candidate_id, created_at)
+			SELECT campaign, key, ?, ? FROM candidates WHERE id = ?`, []any{targetID, timestamp, sourceID}},
+		{`UPDATE candidate_aliases SET candidate_id = ? WHERE candidate_id = ?`, []any{targetID, sourceID}},
+	}
+	for _, table := range resultTables {
+		// Rows keep the key they were stored under, so the source can still be erased on its own
+		statements = append(statements,
+			statement{fmt.Sprintf(`UPDATE %s SET source_key = (SELECT key FROM candidates WHERE id = ?)
+				WHERE candidate_id = ? AND source_key = ''`, table), []any{sourceID, sourceID}},
+			statement{fmt.Sprintf(`UPDATE %s SET candidate_id = ? WHERE candidate_id = ?`, table), []any{targetID, sourceID}})
+	}
+	statements = append(statements,
+		statement{`DELETE FROM candidates WHERE id = ?`, []any{sourceID}},
+		statement{`UPDATE candidates SET updated_at = ? WHERE id = ?`, []any{timestamp, targetID}})
+	for _, statement := range statements {
+		if _, err := tx.Exec(statement.query, statement.args...); err != nil {
+			return fmt.Errorf("failed to merge candidates: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to merge candidates: %w", err)
+	}
+	return nil
+}
+
+// DeleteCandidate implements the CandidateStore interface. Deleted content is overwritten
+// (secure_delete) and the WAL is checkpointed, so it does not linger in the database files.
+func (s *SQLiteStore) DeleteCandidate(id int64) ([]interfaces.CandidateRef, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start delete: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`
+		SELECT campaign, key FROM candidates WHERE id = ?
+		UNION ALL
+		SELECT campaign, key FROM candidate_aliases WHERE candidate_id = ?`, id, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read candidate: %w", err)
+	}
+	var refs []interfaces.CandidateRef
+	for rows.Next() {
+		var ref interfaces.CandidateRef
+		if err := rows.Scan(&ref.Campaign, &ref.Key); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to read candidate: %w", err)
+		}
+		refs = append(refs, ref)
+	}
+	rows.Close()
+	if len(refs) == 0 {
+		return nil, fmt.Errorf("candidate %d not found", id)
+	}
+
+	// Documents, summaries, extractions, scores and aliases are removed by ON DELETE CASCADE
+	if _, err := tx.Exec(`DELETE FROM candidates WHERE id = ?`, id); err != nil {
+		return nil, fmt.Errorf("failed to delete candidate: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to delete candidate: %w", err)
+	}
+
+	if _, err := s.db.Exec(`PRAGMA wal_checkpoint(TRUNCATE)`); err != nil {
+		return refs, fmt.Errorf("failed to checkpoint database: %w", err)
+	}
+	return refs, nil
+}
+
+// FindCandidates implements the CandidateStore interface
+func (s *SQLiteStore) FindCandidates(campaign string, key string) ([]interfaces.CandidateMatch, error) {
+	rows, err := s.db.Query(`
+		SELECT id, campaign, key, 0 FROM candidates WHERE key = ? AND (? = '' OR campaign = ?)
+		UNION ALL
+		SELECT candidate_id, campaign, key, 1 FROM candidate_aliases WHERE key = ? AND (? = '' OR campaign = ?)
+		ORDER BY 2`, key, campaign, campaign, key, campaign, campaign)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find candidate: %w", err)
+	}
+	defer rows.Close()
+
+	var matches []interfaces.CandidateMatch
+	for rows.Next() {
+		var match interfaces.CandidateMatch
+		if err := rows.Scan(&match.ID, &match.Ref.Campaign, &match.Ref.Key, &match.Alias); err != nil {
+			return nil, fmt.Errorf("failed to read candidate: %w", err)
+		}
+		matches = append(matches, match)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to find candidate: %w", err)
+	}
+	return matches, nil
+}
+
+// DeleteAlias implements the CandidateStore interface. Like DeleteCandidate, deleted content
+// is overwritten and the WAL is checkpointed.
+func (s *SQLiteStore) DeleteAlias(ref interfaces.CandidateRef) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to start delete: %w", err)
+	}
+	defer tx.Rollback()
+
+	var candidateID int64
+	err = tx.QueryRow(`SELECT candidate_id FROM candidate_aliases WHERE campaign = ? AND key = ?`,
+		ref.Campaign, ref.Key).Scan(&candidateID)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("alias %s/%s not found", ref.Campaign, ref.Key)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to look up candidate alias: %w", err)
+	}
+
+	for _, table := range resultTables {
+		query := fmt.Sprintf(`DELETE FROM %s WHERE candidate_id = ? AND source_key = ?`, table)
+		if _, err := tx.Exec(query, candidateID, ref.Key); err != nil {
+			return fmt.Errorf("failed to delete alias: %w", err)
+		}
+	}
+	if _, err := tx.Exec(`DELETE FROM candidate_aliases WHERE campaign = ? AND key = ?`, ref.Campaign, ref.Key); err != nil {
+		return fmt.Errorf("failed to delete alias: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to delete alias: %w", err)
+	}
+
+	if _, err := s.db.Exec(`PRAGMA wal_checkpoint(TRUNCATE)`); err != nil {
+		return fmt.Errorf("failed to checkpoint database: %w", err)
+	}
+	return nil
+}
+
+// Close implements the CandidateStore interface
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+// latestExtraction returns the newest extraction of a candidate, or nil if there is none
+func (s *SQLiteStore) latestExtraction(candidateID int64) (*interfaces.ApplicantInfo, error) {
+	var applicant interfaces.ApplicantInfo
+	var unverified string
+	err := s.db.QueryRow(`
+		SELECT name, role, seniority, status, current_position, current_company,
+			years_of_exp, cv_link, skillset, remarks, email, phone, location, linkedin, github, portfolio, unverified
+		FROM extractions WHERE candidate_id = ? ORDER BY id DESC LIMIT 1`, candidateID).Scan(
+		&applicant.Name, &applicant.Role, &applicant.Seniority, &applicant.Status,
+		&applicant.CurrentPosition, &applicant.CurrentCompany, &applicant.YearsOfExp,
+		&applicant.CVLink, &applicant.Skillset, &applicant.Remarks, &applicant.Email, &applicant.Phone,
+		&applicant.Location, &applicant.LinkedIn, &applicant.GitHub, &applicant.Portfolio, &unverified,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read extraction: %w", err)
+	}
+	err = s.open(&applicant.Name, &applicant.Role, &applicant.Seniority, &applicant.Status,
+		&applicant.CurrentPosition, &applicant.CurrentCompany, &applicant.YearsOfExp,
+		&applicant.CVLink, &applicant.Skillset, &applicant.Remarks, &applicant.Email, &applicant.Phone,
+		&applicant.Location, &applicant.LinkedIn, &applicant.GitHub, &applicant.Portfolio, &unverified)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(unverified), &applicant.Unverified); err != nil {
+		return nil, fmt.Errorf("failed to decode unverified values: %w", err)
+	}
+	return &applicant, nil
+}
+
+// upsertCandidate returns the ID of the candidate, creating it if needed.
+// Candidates that were merged into another one resolve to the merge target.
+// A non-empty name replaces the stored one.
+func (s *SQLiteStore) upsertCandidate(candidate interfaces.CandidateRef, name string) (int64, error) {
+	timestamp := now()
+	sealed, err := s.seal(strings.TrimSpace(name))
+	if err != nil {
+		return 0, err
+	}
+
+	var aliasID int64
+	err = s.db.QueryRow(`SELECT candidate_id FROM candidate_aliases WHERE campaign = ? AND key = ?`,
+		candidate.Campaign, candidate.Key).Scan(&aliasID)
+	if err == nil {
+		_, err = s.db.Exec(`
+			UPDATE candidates SET
+				name = CASE WHEN ? <> '' THEN ? ELSE name END,
+				updated_at = ?
+			WHERE id = ?`, sealed[0], sealed[0], timestamp, aliasID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to save candidate: %w", err)
+		}
+		return aliasID, nil
+	}
+	if err != sql.ErrNoRows {
+		return 0, fmt.Errorf("failed to look up candidate alias: %w", err)
+	}
+
+	_, err = s.db.Exec(`
+		INSERT INTO candidates (campaign, key, name, created_at, updated_at) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (campaign, key) DO UPDATE SET
+			name = CASE WHEN excluded.name <> '' THEN excluded.name ELSE candidates.name END,
+			updated_at = excluded.updated_at`,
+		candidate.Campaign, candidate.Key, sealed[0], timestamp, timestamp,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to save candidate: %w", err)
+	}
+
+	var id int64
+	err = s.db.QueryRow(`SELECT id FROM candidates WHERE campaign = ? AND key = ?`, candidate.Campaign, candidate.Key).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to look up candidate: %w", err)
+	}
+	return id, nil
+}
+
+// migrateColumns adds every missing column listed in columnMigrations
+func migrateColumns(db *sql.DB) error {
+	for _, migration := range columnMigrations {
+		var count int
+		err := db.QueryRow(`SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`, migration.table, migration.column).Scan(&count)
+		if err != nil {
+			return fmt.Errorf("failed to inspect table %s: %w", migration.table, err)
+		}
+		if count > 0 {
+			continue
+		}
+		_, err = db.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, migration.table, migration.column, migration.definition))
+		if err != nil {
+			return fmt.Errorf("failed to add column %s.%s: %w", migration.table, migration.column, err)
+		}
+	}
+	return nil
+}
+
+// seal returns the values to store, encrypted if a cipher is configured. Empty values are
+// stored as they are, so checks for empty values in queries keep working.
+func (s *SQLiteStore) seal(values ...string) ([]any, error) {
+	sealed := make([]any, len(values))
+	for i, value := range values {
+		if s.cipher == nil || value == "" {
+			sealed[i] = value
+			continue
+		}
+		ciphertext, err := s.cipher.Encrypt([]byte(value))
+		if err != nil {
+			return nil, err
+		}
+		sealed[i] = ciphertext
+	}
+	return sealed, nil
+}
+
+// open decrypts the values that were stored with a cipher in place
+func (s *SQLiteStore) open(values ...*string) error {
+	for _, value := range values {
+		if !envelope.IsEncrypted([]byte(*value)) {
+			continue
+		}
+		if s.cipher == nil {
+			return envelope.ErrEncrypted
+		}
+		plaintext, err := s.cipher.Decrypt([]byte(*value))
+		if err != nil {
+			return err
+		}
+		*value = string(plaintext)
+	}
+	return nil
+}
+
+// nullableRunID stores runs that were not recorded as NULL
+func nullableRunID(runID int64) any {
+	if runID == 0 {
+		return nil
+	}
+	return runID
+}
+
+func now() string {
+	return time.Now().UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,46 @@
+package sqlite
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/nicoalimin/resume-analyzer/interfaces"
+)
+
+func TestSaveDocumentRerun(t *testing.T) {
+	store, err := NewSQLiteStore(filepath.Join(t.TempDir(), "candidates.db"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	ref := interfaces.CandidateRef{Campaign: "default", Key: "jane_doe"}
+	for i, doc := range []interfaces.Document{
+		{Path: "input_pdfs/jane_doe.pdf", SHA256: "aaa", OCRText: "first OCR run"},
+		{Path: "input_pdfs/jane_doe.pdf", SHA256: "aaa", OCRText: "second OCR run"},
+		{Path: "input_pdfs/jane_doe.pdf", SHA256: "bbb", OCRText: "updated CV"},
+	} {
+		runID, err := store.StartRun("convert-pdfs", "default", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := store.SaveDocument(runID, ref, doc); err != nil {
+			t.Fatalf("SaveDocument() #%d: %v", i+1, err)
+		}
+	}
+
+	candidates, err := store.ListCandidates("default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The rerun on the same file replaced its document; the changed file was added
+	if len(candidates) != 1 || candidates[0].Documents != 2 || candidates[0].Document.OCRText != "updated CV" {
+		t.Fatalf("candidates = %+v, want two documents", candidates)
+	}
+
+	var text string
+	err = store.(*SQLiteStore).db.QueryRow(`SELECT ocr_text FROM documents WHERE sha256 = 'aaa'`).Scan(&text)
+	if err != nil || text != "second OCR run" {
+		t.Errorf("document of the rerun = %q, %v, want the second OCR run", text, err)
+	}
+}
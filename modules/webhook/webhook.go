@@ -0,0 +1,250 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Event types
+const (
+	// EventCandidateSucceeded is sent when a resume of a job has been analyzed
+	EventCandidateSucceeded = "candidate.succeeded"
+	// EventCandidateFailed is sent when a resume of a job could not be analyzed after all attempts
+	EventCandidateFailed = "candidate.failed"
+	// EventJobSucceeded is sent when every resume of a job is finished and at least one succeeded
+	EventJobSucceeded = "job.succeeded"
+	// EventJobFailed is sent when every resume of a job failed
+	EventJobFailed = "job.failed"
+	// EventJobCanceled is sent when a job was canceled
+	EventJobCanceled = "job.canceled"
+	// EventPing is sent by the webhooks test command
+	EventPing = "ping"
+)
+
+// Header names of a delivery
+const (
+	HeaderEvent     = "X-Resume-Analyzer-Event"
+	HeaderDelivery  = "X-Resume-Analyzer-Delivery"
+	HeaderTimestamp = "X-Resume-Analyzer-Timestamp"
+	HeaderSignature = "X-Resume-Analyzer-Signature"
+)
+
+// Endpoint is a URL that events are posted to
+type Endpoint struct {
+	URL string `mapstructure:"url"`
+	// Secret signs every delivery; deliveries are unsigned without one
+	Secret string `mapstructure:"secret"`
+	// SecretEnv names an environment variable holding the secret, instead of Secret
+	SecretEnv string `mapstructure:"secret_env"`
+	// Events limits the event types sent to the endpoint; all events are sent if it is empty
+	Events []string `mapstructure:"events"`
+	// Team limits the events sent to the endpoint to those of one team workspace; the events
+	// of every team are sent if it is empty
+	Team string `mapstructure:"team"`
+}
+
+// wants reports whether the endpoint subscribed to an event type of a team
+func (e Endpoint) wants(team string, eventType string) bool {
+	if eventType == EventPing {
+		return true
+	}
+	if e.Team != "" && e.Team != team {
+		return false
+	}
+	if len(e.Events) == 0 {
+		return true
+	}
+	for _, subscribed := range e.Events {
+		if subscribed == eventType || subscribed == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// Event is the JSON body of a delivery
+type Event struct {
+	// ID is the same for every attempt to deliver the event, so receivers can drop duplicates
+	ID   string    `json:"id"`
+	Type string    `json:"type"`
+	Time time.Time `json:"time"`
+	Data any       `json:"data"`
+}
+
+// Sign returns the signature of a delivery: the hex HMAC-SHA256 of "<timestamp>.<body>" with
+// the endpoint secret, prefixed with "sha256=". Receivers recompute it to check that the
+// delivery is authentic and reject old timestamps to prevent replays.
+func Sign(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Dispatcher posts events to the configured endpoints in the background. Failed deliveries
+// are retried with exponential backoff.
+type Dispatcher struct {
+	endpoints   []Endpoint
+	client      *http.Client
+	maxAttempts int
+	retryDelay  time.Duration
+	// OnFailure is called when a delivery has failed for the last time, if set
+	OnFailure func(endpoint string, event Event, err error)
+
+	ctx     context.Context
+	cancel  context.CancelFunc
+	pending sync.WaitGroup
+}
+
+// NewDispatcher returns a Dispatcher for the endpoints. A delivery is attempted up to
+// maxAttempts times, first retried after retryDelay, with the delay doubling every time.
+func NewDispatcher(endpoints []Endpoint, maxAttempts int, retryDelay time.Duration) *Dispatcher {
+	if maxAttempts <= 0 {
+		maxAttempts = 1
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Dispatcher{
+		endpoints:   endpoints,
+		client:      &http.Client{Timeout: 10 * time.Second},
+		maxAttempts: maxAttempts,
+		retryDelay:  retryDelay,
+		ctx:         ctx,
+		cancel:      cancel,
+	}
+}
+
+// Send delivers an event of a team to every endpoint that subscribed to its type and team
+// without waiting
+func (d *Dispatcher) Send(team string, eventType string, data any) {
+	if d == nil {
+		return
+	}
+	event := newEvent(eventType, data)
+	for _, endpoint := range d.endpoints {
+		if !endpoint.wants(team, eventType) {
+			continue
+		}
+		d.pending.Add(1)
+		go func(endpoint Endpoint) {
+			defer d.pending.Done()
+			if err := d.deliver(endpoint, event); err != nil && d.OnFailure != nil {
+				d.OnFailure(endpoint.URL, event, err)
+			}
+		}(endpoint)
+	}
+}
+
+// SendNow delivers an event of no team to every endpoint and waits for the deliveries,
+// including retries. The errors are returned by endpoint URL.
+func (d *Dispatcher) SendNow(eventType string, data any) map[string]error {
+	event := newEvent(eventType, data)
+	results := map[string]error{}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, endpoint := range d.endpoints {
+		if !endpoint.wants("", eventType) {
+			continue
+		}
+		wg.Add(1)
+		go func(endpoint Endpoint) {
+			defer wg.Done()
+			err := d.deliver(endpoint, event)
+			mu.Lock()
+			results[endpoint.URL] = err
+			mu.Unlock()
+		}(endpoint)
+	}
+	wg.Wait()
+	return results
+}
+
+// Close waits up to timeout for pending deliveries and then abandons their retries
+func (d *Dispatcher) Close(timeout time.Duration) {
+	if d == nil {
+		return
+	}
+	done := make(chan struct{})
+	go func() {
+		d.pending.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		d.cancel()
+		<-done
+	}
+}
+
+// deliver posts an event to an endpoint, retrying on network errors, 429 and 5xx responses
+func (d *Dispatcher) deliver(endpoint Endpoint, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	delay := d.retryDelay
+	for attempt := 1; ; attempt++ {
+		retry, err := d.post(endpoint, event, body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= d.maxAttempts {
+			return fmt.Errorf("attempt %d: %w", attempt, err)
+		}
+		select {
+		case <-time.After(delay):
+		case <-d.ctx.Done():
+			return fmt.Errorf("attempt %d: %w (retries abandoned)", attempt, err)
+		}
+		delay *= 2
+	}
+}
+
+// post makes one delivery attempt and reports whether a failure is worth retrying
+func (d *Dispatcher) post(endpoint Endpoint, event Event, body []byte) (bool, error) {
+	request, err := http.NewRequestWithContext(d.ctx, http.MethodPost, endpoint.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", "resume-analyzer-webhook")
+	request.Header.Set(HeaderEvent, event.Type)
+	request.Header.Set(HeaderDelivery, event.ID)
+	request.Header.Set(HeaderTimestamp, timestamp)
+	if endpoint.Secret != "" {
+		request.Header.Set(HeaderSignature, Sign(endpoint.Secret, timestamp, body))
+	}
+
+	response, err := d.client.Do(request)
+	if err != nil {
+		return true, err
+	}
+	defer response.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(response.Body, 64<<10))
+
+	if response.StatusCode >= 200 && response.StatusCode < 300 {
+		return false, nil
+	}
+	retry := response.StatusCode == http.StatusTooManyRequests || response.StatusCode >= 500
+	return retry, fmt.Errorf("endpoint responded %s", response.Status)
+}
+
+func newEvent(eventType string, data any) Event {
+	id := make([]byte, 12)
+	rand.Read(id)
+	return Event{ID: hex.EncodeToString(id), Type: eventType, Time: time.Now().UTC(), Data: data}
+}
@@ -0,0 +1,245 @@
+package pipeline
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/nicoalimin/resume-analyzer/interfaces"
+)
+
+// BlindIDPattern matches the anonymous candidate IDs handed out in blind mode
+var BlindIDPattern = regexp.MustCompile(`\bCAND-[0-9A-F]{8}\b`)
+
+// sensitiveLabelPattern matches "Label: value" lines for attributes that are removed in blind mode
+var sensitiveLabelPattern = regexp.MustCompile(`(?im)^([\W\d]*(full name|name|gender|sex|age|date of birth|dob|nationality|citizenship|marital status|religion|photo|photograph)\W*[:\-]\s*)(.+)$`)
+
+var photoPattern = regexp.MustCompile(`(?im)^.*\bphoto(graph)?s?\b.*$\n?`)
+var agePattern = regexp.MustCompile(`(?i)\b\d{2}\s*(years old|yrs old|y/o|year-old)\b`)
+var titlePattern = regexp.MustCompile(`\b(Mr|Mrs|Ms|Miss|Mx)\.?\s+`)
+var schoolPattern = regexp.MustCompile(`\b(?:[A-Z][\w&'.\-]*\s+){0,5}(?:University|College|Institute|Polytechnic|School|Academy)(?:\s+of(?:\s+[A-Z][\w&'.\-]*){1,4})?\b`)
+
+// pronouns maps gendered pronouns to their neutral form. "her" is either possessive or an
+// object, see neutralPronoun.
+var pronouns = map[string]string{
+	"he": "they", "she": "they", "him": "them", "his": "their", "her": "their",
+	"hers": "theirs", "himself": "themselves", "herself": "themselves",
+}
+var pronounPattern = regexp.MustCompile(`(?i)\b(he|she|him|his|her|hers|himself|herself)\b`)
+var theyVerbPattern = regexp.MustCompile(`(?i)\b(they) (is|has|was)\b`)
+var nextWordPattern = regexp.MustCompile(`^\s+([A-Za-z']+)`)
+
+// objectFollowers are words that follow "her" as an object ("hired her as lead", "gave her
+// the role"), where a noun after it would make it possessive ("her team")
+var objectFollowers = map[string]bool{
+	"a": true, "an": true, "the": true, "and": true, "or": true, "but": true, "as": true,
+	"to": true, "for": true, "with": true, "in": true, "on": true, "at": true, "by": true,
+	"from": true, "into": true, "of": true, "over": true, "after": true, "before": true,
+	"since": true, "because": true, "when": true, "that": true, "this": true, "again": true,
+	"back": true, "up": true, "out": true, "down": true, "off": true,
+}
+
+// BlindCandidate is the identity behind an anonymous candidate ID
+type BlindCandidate struct {
+	Key  string `json:"key"`
+	Name string `json:"name,omitempty"`
+}
+
+// BlindMapping links anonymous candidate IDs to the candidates they stand for.
+// IDs are derived from the candidate key with a secret salt, so they are stable across
+// runs but cannot be guessed from a list of names.
+type BlindMapping struct {
+	Salt       string                    `json:"salt"`
+	Candidates map[string]BlindCandidate `json:"candidates"`
+
+	path  string
+	files interfaces.FileStore
+}
+
+// LoadBlindMapping reads the mapping file, creating a new salt if there is none yet.
+// files may be nil to read and write plain files.
+func LoadBlindMapping(files interfaces.FileStore, path string) (*BlindMapping, error) {
+	if files == nil {
+		files = osFiles{}
+	}
+	mapping := &BlindMapping{Candidates: map[string]BlindCandidate{}, path: path, files: files}
+
+	data, err := files.ReadFile(path)
+	if os.IsNotExist(err) {
+		salt := make([]byte, 16)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, fmt.Errorf("failed to create salt: %w", err)
+		}
+		mapping.Salt = hex.EncodeToString(salt)
+		return mapping, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read blind mapping: %w", err)
+	}
+	if err := json.Unmarshal(data, mapping); err != nil {
+		return nil, fmt.Errorf("failed to parse blind mapping: %w", err)
+	}
+	if mapping.Candidates == nil {
+		mapping.Candidates = map[string]BlindCandidate{}
+	}
+	return mapping, nil
+}
+
+// Save writes the mapping and locks it against changes and other users
+func (m *BlindMapping) Save() error {
+	path := m.path
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode blind mapping: %w", err)
+	}
+	if _, err := os.Stat(path); err == nil {
+		if err := os.Chmod(path, 0600); err != nil {
+			return fmt.Errorf("failed to unlock blind mapping: %w", err)
+		}
+	}
+	if err := m.files.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write blind mapping: %w", err)
+	}
+	return os.Chmod(path, 0400)
+}
+
+// isBlindID reports whether a key is an anonymous candidate ID rather than a file name
+func isBlindID(key string) bool {
+	return key != "" && BlindIDPattern.FindString(key) == key
+}
+
+// lookup returns the candidate behind an anonymous ID
+func (m *BlindMapping) lookup(id string) (BlindCandidate, bool) {
+	candidate, ok := m.Candidates[id]
+	return candidate, ok && candidate.Key != ""
+}
+
+// idFor returns the anonymous ID of a candidate and records who it belongs to.
+// Keys that already are anonymous IDs, e.g. from blind summaries, are kept.
+func (m *BlindMapping) idFor(key string, name string) string {
+	if _, ok := m.Candidates[key]; ok && isBlindID(key) {
+		return key
+	}
+
+	mac := hmac.New(sha256.New, []byte(m.Salt))
+	mac.Write([]byte(key))
+	id := "CAND-" + strings.ToUpper(hex.EncodeToString(mac.Sum(nil))[:8])
+
+	candidate := m.Candidates[id]
+	candidate.Key = key
+	if candidate.Name == "" && HasValue(name) {
+		candidate.Name = name
+	}
+	m.Candidates[id] = candidate
+	return id
+}
+
+// blindText removes names and bias-prone attributes from free text
+func blindText(text string, id string, names ...string) string {
+	text = sensitiveLabelPattern.ReplaceAllStringFunc(text, func(line string) string {
+		match := sensitiveLabelPattern.FindStringSubmatch(line)
+		if label := strings.ToLower(match[2]); label == "name" || label == "full name" {
+			return match[1] + id
+		}
+		return match[1] + "[removed]"
+	})
+	text = photoPattern.ReplaceAllString(text, "")
+	text = agePattern.ReplaceAllString(text, "[removed]")
+	text = titlePattern.ReplaceAllString(text, "")
+	text = schoolPattern.ReplaceAllString(text, "[school]")
+
+	if pattern := namePattern(names); pattern != nil {
+		text = pattern.ReplaceAllString(text, id)
+	}
+
+	var neutral strings.Builder
+	last := 0
+	for _, loc := range pronounPattern.FindAllStringIndex(text, -1) {
+		neutral.WriteString(text[last:loc[0]])
+		neutral.WriteString(neutralPronoun(text[loc[0]:loc[1]], text[loc[1]:]))
+		last = loc[1]
+	}
+	neutral.WriteString(text[last:])
+	return theyVerbPattern.ReplaceAllStringFunc(neutral.String(), func(phrase string) string {
+		match := theyVerbPattern.FindStringSubmatch(phrase)
+		verb := map[string]string{"is": "are", "has": "have", "was": "were"}[strings.ToLower(match[2])]
+		return match[1] + " " + verb
+	})
+}
+
+// neutralPronoun returns the neutral form of a gendered pronoun, given the text after it.
+// "her" is an object ("them") unless a noun follows it ("their").
+func neutralPronoun(word string, rest string) string {
+	lower := strings.ToLower(word)
+	if lower == "her" {
+		next := nextWordPattern.FindStringSubmatch(rest)
+		if next == nil || objectFollowers[strings.ToLower(next[1])] {
+			return MatchCase("them", word)
+		}
+	}
+	return MatchCase(pronouns[lower], word)
+}
+
+// namePattern returns one regexp matching the names and each of their parts, or nil if there
+// are none. Longer alternatives go first so that "Jane Doe" becomes one ID rather than two.
+func namePattern(names []string) *regexp.Regexp {
+	seen := map[string]bool{}
+	var parts []string
+	for _, name := range names {
+		if !HasValue(name) {
+			continue
+		}
+		for _, part := range append([]string{name}, strings.Fields(name)...) {
+			part = strings.Trim(part, ".,")
+			if len(part) < 3 || seen[strings.ToLower(part)] {
+				continue
+			}
+			seen[strings.ToLower(part)] = true
+			parts = append(parts, regexp.QuoteMeta(part))
+		}
+	}
+	if len(parts) == 0 {
+		return nil
+	}
+	sort.SliceStable(parts, func(i, j int) bool { return len(parts[i]) > len(parts[j]) })
+	return regexp.MustCompile(`(?i)\b(?:` + strings.Join(parts, "|") + `)\b`)
+}
+
+// blindApplicant replaces the name with the anonymous ID and removes contact details,
+// links and bias-prone attributes from the table fields
+func blindApplicant(applicant *ApplicantInfo, id string) {
+	name := applicant.Name
+	applicant.Name = id
+	for _, field := range []*string{
+		&applicant.Email, &applicant.Phone, &applicant.Location, &applicant.LinkedIn,
+		&applicant.GitHub, &applicant.Portfolio, &applicant.CVLink,
+	} {
+		*field = "N/A"
+	}
+	for _, field := range []*string{
+		&applicant.Role, &applicant.CurrentPosition, &applicant.CurrentCompany,
+		&applicant.Skillset, &applicant.Remarks,
+	} {
+		*field = blindText(*field, id, name)
+	}
+	// Unverified values quote the original fields, including the name
+	applicant.Unverified = nil
+}
+
+// MatchCase gives replacement the capitalization of original
+func MatchCase(replacement string, original string) string {
+	switch {
+	case original == strings.ToUpper(original):
+		return strings.ToUpper(replacement)
+	case original[:1] == strings.ToUpper(original[:1]):
+		return strings.ToUpper(replacement[:1]) + replacement[1:]
+	}
+	return replacement
+}
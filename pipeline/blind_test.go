@@ -0,0 +1,22 @@
+package pipeline
+
+import "testing"
+
+func TestBlindTextPronouns(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"She led her team.", "They led their team."},
+		{"Acme hired her as lead.", "Acme hired them as lead."},
+		{"Her manager promoted her.", "Their manager promoted them."},
+		{"They gave her the role and trusted her", "They gave them the role and trusted them"},
+		{"He has shipped his own compiler himself.", "They have shipped their own compiler themselves."},
+		{"Jane Doe is a senior engineer.", "CAND-00000001 is a senior engineer."},
+	}
+	for _, test := range tests {
+		if got := blindText(test.text, "CAND-00000001", "Jane Doe"); got != test.want {
+			t.Errorf("blindText(%q) = %q, want %q", test.text, got, test.want)
+		}
+	}
+}
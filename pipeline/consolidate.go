@@ -0,0 +1,375 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/nicoalimin/resume-analyzer/interfaces"
+	"github.com/nicoalimin/resume-analyzer/prompts"
+)
+
+// ConsolidateOptions configures Consolidate
+type ConsolidateOptions struct {
+	Env
+	// LLM extracts the fields from summaries and, with ContactFallback, finds missing contact details
+	LLM interfaces.LLMService
+	// InputDir is the folder containing summaries or JSON files written by Extract
+	InputDir string
+	// FromStore reads the latest extracted data of the campaign from Store instead of InputDir
+	FromStore bool
+	// OutputFile is the file the table is written to; nothing is written if it is empty
+	OutputFile string
+	// Format is csv, json, jsonl or xlsx (default: inferred from OutputFile, falling back to csv)
+	Format string
+	// SourceDir is the folder containing the files written by Convert, used to verify the
+	// extracted values and to find contact details and hyperlinks (optional)
+	SourceDir string
+	// PDFDir is the folder containing the original PDFs, used for the CV links (optional)
+	PDFDir string
+	// CVBaseURL is the URL the PDFs are published under; CV links become <CVBaseURL>/<name>.pdf (optional)
+	CVBaseURL string
+	// ContactFallback asks the LLM for contact details that pattern matching could not find
+	ContactFallback bool
+	// Blind, if set, replaces names with anonymous candidate IDs and removes contact details
+	// and bias-prone attributes. New IDs are saved to the mapping.
+	Blind *BlindMapping
+}
+
+// ConsolidateResult is the result of Consolidate
+type ConsolidateResult struct {
+	Applicants []ApplicantInfo
+	// Format is the format the table was rendered in
+	Format string
+	// Table is the rendered table
+	Table  []byte
+	Failed []FileError
+}
+
+// Consolidate extracts the applicant information from every summary or extracted JSON file
+// and renders it as a single table. JSON files written by Extract are read as-is without
+// another LLM call.
+func Consolidate(opts ConsolidateOptions) (*ConsolidateResult, error) {
+	if opts.InputDir == "" && !opts.FromStore {
+		return nil, errors.New("an input folder must be specified")
+	}
+	if opts.FromStore && opts.Store == nil {
+		return nil, errors.New("reading from the candidate store requires a store")
+	}
+
+	result := &ConsolidateResult{Format: opts.Format}
+	if result.Format == "" {
+		result.Format = formatFromExtension(opts.OutputFile)
+	}
+
+	var err error
+	if opts.FromStore {
+		err = opts.loadApplicantsFromStore(result)
+	} else {
+		err = opts.loadApplicantsFromDir(result)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load applicants: %w", err)
+	}
+
+	if opts.Blind != nil {
+		if err := opts.blindApplicants(result.Applicants); err != nil {
+			return nil, fmt.Errorf("failed to anonymize applicants: %w", err)
+		}
+	}
+
+	// Generate the consolidated table in the requested format
+	switch result.Format {
+	case "csv":
+		result.Table = []byte(generateConsolidatedTable(result.Applicants))
+	case "json":
+		result.Table, err = generateConsolidatedJSON(result.Applicants, time.Now())
+	case "jsonl":
+		result.Table, err = generateConsolidatedJSONL(result.Applicants, time.Now())
+	case "xlsx":
+		result.Table, err = generateConsolidatedXLSX(result.Applicants)
+	default:
+		return nil, fmt.Errorf("unsupported format %q (expected csv, json, jsonl or xlsx)", result.Format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate consolidated table: %w", err)
+	}
+
+	if opts.OutputFile != "" {
+		if err := opts.writeFile(opts.OutputFile, result.Table, 0644); err != nil {
+			return nil, fmt.Errorf("failed to write consolidated table: %w", err)
+		}
+	}
+	return result, nil
+}
+
+// loadApplicantsFromDir extracts applicant information from every summary or extracted JSON file in InputDir
+func (o *ConsolidateOptions) loadApplicantsFromDir(result *ConsolidateResult) error {
+	files, err := os.ReadDir(o.InputDir)
+	if err != nil {
+		return fmt.Errorf("failed to read input directory: %w", err)
+	}
+
+	for _, file := range files {
+		var baseName string
+		switch {
+		case file.IsDir():
+			continue
+		case strings.HasSuffix(file.Name(), "_summary.txt"):
+			baseName = strings.TrimSuffix(file.Name(), "_summary.txt")
+		case strings.HasSuffix(file.Name(), ".links.json"):
+			continue
+		case strings.HasSuffix(file.Name(), ".json"):
+			baseName = strings.TrimSuffix(file.Name(), ".json")
+		default:
+			continue
+		}
+
+		inputPath := filepath.Join(o.InputDir, file.Name())
+		o.started(file.Name())
+
+		// Read the summary file
+		content, err := o.readFile(inputPath)
+		if err != nil {
+			result.Failed = append(result.Failed, o.fail(file.Name(), fmt.Errorf("failed to read %s: %w", file.Name(), err)))
+			continue
+		}
+
+		var applicant ApplicantInfo
+		if strings.HasSuffix(file.Name(), ".json") {
+			// Files written by the extract command already hold the structured fields
+			err = json.Unmarshal(content, &applicant)
+		} else {
+			// Extract structured information using LLM service
+			applicant, err = o.extractApplicantInfo(baseName, string(content), file.Name())
+		}
+		if err != nil {
+			result.Failed = append(result.Failed, o.fail(file.Name(), fmt.Errorf("failed to extract info from %s: %w", file.Name(), err)))
+			continue
+		}
+
+		applicant.SourceFile = file.Name()
+		if !isBlindID(baseName) {
+			o.enrichApplicantInfo(&applicant, baseName, interfaces.Document{})
+			o.storeExtraction(baseName, applicant)
+		} else if candidate, ok := o.blindCandidate(baseName); ok {
+			// Blind summaries are named after the anonymous ID. Their fields are anonymized, so
+			// they are checked against the original text under the original name, which
+			// blindApplicants replaces again, but never stored over the candidate.
+			if HasValue(candidate.Name) {
+				applicant.Name = candidate.Name
+			}
+			o.enrichApplicantInfo(&applicant, candidate.Key, interfaces.Document{})
+		}
+
+		result.Applicants = append(result.Applicants, applicant)
+	}
+	return nil
+}
+
+// blindCandidate returns the candidate behind the anonymous ID of a blind summary. Without a
+// blind mapping the candidate stays anonymous.
+func (o *ConsolidateOptions) blindCandidate(id string) (BlindCandidate, bool) {
+	if o.Blind == nil {
+		return BlindCandidate{}, false
+	}
+	return o.Blind.lookup(id)
+}
+
+// blindApplicants anonymizes every applicant and records the anonymous IDs in the blind mapping
+func (o *ConsolidateOptions) blindApplicants(applicants []ApplicantInfo) error {
+	for i := range applicants {
+		id := o.Blind.idFor(CandidateBaseName(applicants[i].SourceFile), applicants[i].Name)
+		blindApplicant(&applicants[i], id)
+		applicants[i].SourceFile = id
+	}
+	return o.Blind.Save()
+}
+
+// loadApplicantsFromStore returns the latest extraction of every candidate in the campaign
+func (o *ConsolidateOptions) loadApplicantsFromStore(result *ConsolidateResult) error {
+	candidates, err := o.Store.ListCandidates(o.campaign())
+	if err != nil {
+		return err
+	}
+
+	for _, candidate := range candidates {
+		if candidate.Extraction == nil {
+			o.warn(candidate.Key, fmt.Errorf("skipping %s: no extracted data in the database", candidate.Key))
+			continue
+		}
+		o.started(candidate.Key)
+
+		applicant := *candidate.Extraction
+		applicant.SourceFile = candidate.Key
+		o.enrichApplicantInfo(&applicant, candidate.Key, candidate.Document)
+		result.Applicants = append(result.Applicants, applicant)
+	}
+	return nil
+}
+
+// enrichApplicantInfo fills in the CV link, profile links and contact details and verifies the
+// extracted values. The original OCR text and hyperlinks are read from SourceDir, falling back
+// to the stored document.
+func (o *ConsolidateOptions) enrichApplicantInfo(applicant *ApplicantInfo, baseName string, source interfaces.Document) {
+	if o.PDFDir != "" {
+		pdfPath := filepath.Join(o.PDFDir, baseName+".pdf")
+		if _, err := os.Stat(pdfPath); err == nil {
+			source.Path = pdfPath
+		}
+	}
+
+	// Point the CV link at the original PDF when it is known
+	if !HasValue(applicant.CVLink) {
+		if o.CVBaseURL != "" || source.Path != "" {
+			applicant.CVLink = cvLinkFor(o.CVBaseURL, baseName, source.Path)
+		}
+	}
+
+	if o.SourceDir != "" {
+		sourceName := baseName + ".txt"
+		content, err := o.readFile(filepath.Join(o.SourceDir, sourceName))
+		if err != nil {
+			o.warn(baseName, fmt.Errorf("failed to read source text for %s: %w", baseName, err))
+		} else {
+			source.OCRText = string(content)
+		}
+
+		source.Links, err = o.readLinksFile(o.SourceDir, baseName)
+		if err != nil {
+			o.warn(baseName, fmt.Errorf("failed to read links for %s: %w", baseName, err))
+		}
+	}
+
+	applyLinks(applicant, source.Links, o.PhoneCountryCode)
+
+	sourceText := source.OCRText
+	if sourceText == "" {
+		return
+	}
+
+	// Pull contact details from the OCR text, asking the LLM only for what the patterns miss
+	ctx := context.Background()
+	var fallback interfaces.LLMService
+	if o.ContactFallback {
+		fallback = o.LLM
+		ctx = o.call([]string{baseName}, sourceText)
+	}
+	if err := applyContactDetails(ctx, applicant, sourceText, source.Links, fallback, o.PhoneCountryCode); err != nil {
+		o.warn(baseName, err)
+	}
+
+	// Cross-check the extracted values against the original OCR text
+	applicant.Unverified = verifyApplicantInfo(*applicant, sourceText)
+	for _, value := range applicant.Unverified {
+		o.warn(baseName, fmt.Errorf("unverified value in %s: %s", baseName, value))
+	}
+}
+
+func (o *ConsolidateOptions) extractApplicantInfo(key string, summary string, filename string) (ApplicantInfo, error) {
+	if o.LLM == nil {
+		return ApplicantInfo{}, errors.New("no LLM service given")
+	}
+	prompt := prompts.GetExtractionPrompt(summary)
+
+	response, err := interfaces.GenerateTextContext(o.call([]string{key}, summary), o.LLM, prompt)
+	if err != nil {
+		return ApplicantInfo{}, err
+	}
+
+	// Parse the JSON response (simplified - in production you'd want proper JSON parsing)
+	// For now, we'll create a basic structure
+	applicant := ApplicantInfo{
+		Name:            extractField(response, "name"),
+		Role:            extractField(response, "role"),
+		Seniority:       extractField(response, "seniority"),
+		Status:          extractField(response, "status"),
+		CurrentPosition: extractField(response, "current_position"),
+		CurrentCompany:  extractField(response, "current_company"),
+		YearsOfExp:      extractField(response, "years_of_exp"),
+		CVLink:          extractField(response, "cv_link"),
+		Skillset:        extractField(response, "skillset"),
+		Remarks:         extractField(response, "remarks"),
+	}
+
+	// If name is not found, use filename
+	if applicant.Name == "N/A" || applicant.Name == "" {
+		applicant.Name = strings.TrimSuffix(filename, "_summary.txt")
+	}
+
+	return applicant, nil
+}
+
+func extractField(response, field string) string {
+	// Simple field extraction - in production you'd want proper JSON parsing
+	fieldLower := strings.ToLower(field)
+	responseLower := strings.ToLower(response)
+
+	if strings.Contains(responseLower, `"`+fieldLower+`"`) {
+		// Basic extraction - find the field and get the value
+		start := strings.Index(responseLower, `"`+fieldLower+`"`)
+		if start != -1 {
+			valueStart := strings.Index(response[start:], ":")
+			if valueStart != -1 {
+				valueStart += start + 1
+				valueEnd := strings.Index(response[valueStart:], "\n")
+				if valueEnd == -1 {
+					valueEnd = len(response) - valueStart
+				}
+				value := strings.TrimSpace(response[valueStart : valueStart+valueEnd])
+				value = strings.Trim(value, `",`)
+				return value
+			}
+		}
+	}
+	return "N/A"
+}
+
+func generateConsolidatedTable(applicants []ApplicantInfo) string {
+	var csv strings.Builder
+
+	// CSV header
+	csv.WriteString("Applicant,Role,Seniority,Status,Current Position,Current Company,Years of Exp,CV Link,Skillset,Remarks,Email,Phone,Location,LinkedIn,GitHub,Portfolio,Unverified\n")
+
+	// Data rows
+	for _, applicant := range applicants {
+		// Escape CSV fields that contain commas or quotes
+		row := fmt.Sprintf("%s,%s,%s,%s,%s,%s,%s,%s,%s,%s,%s,%s,%s,%s,%s,%s,%s\n",
+			escapeCSVField(applicant.Name),
+			escapeCSVField(applicant.Role),
+			escapeCSVField(applicant.Seniority),
+			escapeCSVField(applicant.Status),
+			escapeCSVField(applicant.CurrentPosition),
+			escapeCSVField(applicant.CurrentCompany),
+			escapeCSVField(applicant.YearsOfExp),
+			escapeCSVField(applicant.CVLink),
+			escapeCSVField(applicant.Skillset),
+			escapeCSVField(applicant.Remarks),
+			escapeCSVField(applicant.Email),
+			escapeCSVField(applicant.Phone),
+			escapeCSVField(applicant.Location),
+			escapeCSVField(applicant.LinkedIn),
+			escapeCSVField(applicant.GitHub),
+			escapeCSVField(applicant.Portfolio),
+			escapeCSVField(strings.Join(applicant.Unverified, "; ")))
+		csv.WriteString(row)
+	}
+
+	return csv.String()
+}
+
+// escapeCSVField properly escapes CSV fields that contain commas, quotes, or newlines
+func escapeCSVField(field string) string {
+	// If field contains comma, quote, or newline, wrap in quotes and escape internal quotes
+	if strings.ContainsAny(field, ",\"\n\r") {
+		// Replace any existing quotes with double quotes
+		escaped := strings.ReplaceAll(field, "\"", "\"\"")
+		return "\"" + escaped + "\""
+	}
+	return field
+}
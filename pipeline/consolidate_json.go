@@ -0,0 +1,134 @@
+package pipeline
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ApplicantRecord is the typed representation of an applicant used for JSON output
+type ApplicantRecord struct {
+	Name            string    `json:"name"`
+	Role            string    `json:"role"`
+	Seniority       string    `json:"seniority"`
+	Status          string    `json:"status"`
+	CurrentPosition string    `json:"current_position"`
+	CurrentCompany  string    `json:"current_company"`
+	YearsOfExp      *float64  `json:"years_of_exp"`
+	CVLink          string    `json:"cv_link"`
+	Skills          []string  `json:"skills"`
+	Remarks         string    `json:"remarks"`
+	Email           string    `json:"email"`
+	Phone           string    `json:"phone"`
+	Location        string    `json:"location"`
+	LinkedIn        string    `json:"linkedin"`
+	GitHub          string    `json:"github"`
+	Portfolio       string    `json:"portfolio"`
+	Unverified      []string  `json:"unverified"`
+	SourceFile      string    `json:"source_file"`
+	ProcessedAt     time.Time `json:"processed_at"`
+}
+
+var yearsPattern = regexp.MustCompile(`\d+(\.\d+)?`)
+
+// NewApplicantRecord converts the flat string fields of an ApplicantInfo into native types
+func NewApplicantRecord(applicant ApplicantInfo, processedAt time.Time) ApplicantRecord {
+	record := ApplicantRecord{
+		Name:            valueOrEmpty(applicant.Name),
+		Role:            valueOrEmpty(applicant.Role),
+		Seniority:       valueOrEmpty(applicant.Seniority),
+		Status:          valueOrEmpty(applicant.Status),
+		CurrentPosition: valueOrEmpty(applicant.CurrentPosition),
+		CurrentCompany:  valueOrEmpty(applicant.CurrentCompany),
+		YearsOfExp:      ParseYearsOfExp(applicant.YearsOfExp),
+		CVLink:          valueOrEmpty(applicant.CVLink),
+		Skills:          SplitSkillset(applicant.Skillset),
+		Remarks:         valueOrEmpty(applicant.Remarks),
+		Email:           valueOrEmpty(applicant.Email),
+		Phone:           valueOrEmpty(applicant.Phone),
+		Location:        valueOrEmpty(applicant.Location),
+		LinkedIn:        valueOrEmpty(applicant.LinkedIn),
+		GitHub:          valueOrEmpty(applicant.GitHub),
+		Portfolio:       valueOrEmpty(applicant.Portfolio),
+		Unverified:      applicant.Unverified,
+		SourceFile:      applicant.SourceFile,
+		ProcessedAt:     processedAt.UTC(),
+	}
+	if record.Unverified == nil {
+		record.Unverified = []string{}
+	}
+	return record
+}
+
+// generateConsolidatedJSON renders all applicants as a single indented JSON array
+func generateConsolidatedJSON(applicants []ApplicantInfo, processedAt time.Time) ([]byte, error) {
+	records := make([]ApplicantRecord, 0, len(applicants))
+	for _, applicant := range applicants {
+		records = append(records, NewApplicantRecord(applicant, processedAt))
+	}
+	return json.MarshalIndent(records, "", "  ")
+}
+
+// generateConsolidatedJSONL renders one JSON object per line
+func generateConsolidatedJSONL(applicants []ApplicantInfo, processedAt time.Time) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, applicant := range applicants {
+		if err := encoder.Encode(NewApplicantRecord(applicant, processedAt)); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// formatFromExtension infers the consolidate output format from a file name
+func formatFromExtension(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return "json"
+	case ".jsonl", ".ndjson":
+		return "jsonl"
+	case ".xlsx":
+		return "xlsx"
+	default:
+		return "csv"
+	}
+}
+
+// ParseYearsOfExp returns the first number in values like "5 years" or "10+ yrs", or nil if there is none
+func ParseYearsOfExp(value string) *float64 {
+	match := yearsPattern.FindString(value)
+	if match == "" {
+		return nil
+	}
+	years, err := strconv.ParseFloat(match, 64)
+	if err != nil {
+		return nil
+	}
+	return &years
+}
+
+// SplitSkillset splits a comma separated skill list, dropping empty and placeholder entries
+func SplitSkillset(skillset string) []string {
+	skills := []string{}
+	for _, skill := range strings.Split(skillset, ",") {
+		skill = strings.TrimSpace(skill)
+		if skill == "" || strings.EqualFold(skill, "N/A") {
+			continue
+		}
+		skills = append(skills, skill)
+	}
+	return skills
+}
+
+// valueOrEmpty maps the "N/A" placeholder used by the extraction prompt to an empty string
+func valueOrEmpty(value string) string {
+	if strings.EqualFold(strings.TrimSpace(value), "N/A") {
+		return ""
+	}
+	return value
+}
@@ -0,0 +1,213 @@
+package pipeline
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const extractionResponse = `{
+  "name": "Jane Doe",
+  "role": "Backend Engineer",
+  "seniority": "Senior",
+  "status": "Active",
+  "current_position": "Senior Software Engineer",
+  "current_company": "Acme",
+  "years_of_exp": "8 years",
+  "cv_link": "N/A",
+  "skillset": "Go, Kubernetes, Rust",
+  "remarks": "Led the platform team"
+}`
+
+const sourceText = `Jane Doe
+Senior Software Engineer, Acme
+jane.doe@example.com | +65 9123 4567 | linkedin.com/in/janedoe | github.com/janedoe
+Skills: Go, Kubernetes`
+
+// writeFiles writes files relative to dir, creating folders as needed
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// warnings collects the warnings reported through Env.Progress
+func warnings(env *Env) *[]string {
+	var messages []string
+	env.Progress = func(event Event) {
+		if event.Kind == EventWarning || event.Kind == EventFailed {
+			messages = append(messages, event.Err.Error())
+		}
+	}
+	return &messages
+}
+
+func TestConsolidateSummaries(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"summaries/jane_doe_summary.txt": "Jane Doe is a senior backend engineer at Acme.",
+		"summaries/notes.md":             "ignored",
+		"txts/jane_doe.txt":              sourceText,
+		"pdfs/jane_doe.pdf":              "%PDF-1.4",
+	})
+
+	llm := &fakeLLM{respond: respondWhen(map[string]string{"resume summary": extractionResponse})}
+	opts := ConsolidateOptions{
+		LLM:             llm,
+		InputDir:        filepath.Join(dir, "summaries"),
+		OutputFile:      filepath.Join(dir, "applicants.csv"),
+		SourceDir:       filepath.Join(dir, "txts"),
+		PDFDir:          filepath.Join(dir, "pdfs"),
+		ContactFallback: true,
+		Env:             Env{PhoneCountryCode: "65"},
+	}
+	warned := warnings(&opts.Env)
+
+	result, err := Consolidate(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Failed) != 0 || len(result.Applicants) != 1 {
+		t.Fatalf("got %d applicants and failures %v, want 1 applicant", len(result.Applicants), result.Failed)
+	}
+	// Every contact field the patterns look for is in the text, so the LLM is only asked
+	// for the extraction
+	if len(llm.prompts) != 1 {
+		t.Errorf("LLM called %d times, want 1", len(llm.prompts))
+	}
+	if got := llm.calls[0].Candidates; !reflect.DeepEqual(got, []string{"jane_doe"}) {
+		t.Errorf("call candidates = %q, want [jane_doe]", got)
+	}
+
+	applicant := result.Applicants[0]
+	if applicant.Email != "jane.doe@example.com" || applicant.Phone != "+6591234567" || applicant.GitHub != "https://github.com/janedoe" {
+		t.Errorf("contact details = %q, %q, %q", applicant.Email, applicant.Phone, applicant.GitHub)
+	}
+	if !strings.HasPrefix(applicant.CVLink, "file://") || !strings.HasSuffix(applicant.CVLink, "/pdfs/jane_doe.pdf") {
+		t.Errorf("CV link = %q, want an absolute file:// URL", applicant.CVLink)
+	}
+	if !reflect.DeepEqual(applicant.Unverified, []string{"skill: Rust"}) {
+		t.Errorf("unverified = %q, want [skill: Rust]", applicant.Unverified)
+	}
+	if len(*warned) != 1 || !strings.Contains((*warned)[0], "skill: Rust") {
+		t.Errorf("warnings = %q, want the unverified skill", *warned)
+	}
+
+	data, err := os.ReadFile(opts.OutputFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(strings.NewReader(string(data))).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[0][0] != "Applicant" || records[1][0] != "Jane Doe" || records[1][8] != "Go, Kubernetes, Rust" {
+		t.Errorf("table = %q", records)
+	}
+}
+
+func TestConsolidateExtractedJSON(t *testing.T) {
+	dir := t.TempDir()
+	applicant := ApplicantInfo{Name: "John Roe", Role: "Data Engineer", YearsOfExp: "5.5", Email: "N/A"}
+	data, _ := json.Marshal(applicant)
+	writeFiles(t, dir, map[string]string{
+		"john_roe.json":       string(data),
+		"john_roe.links.json": `["https://github.com/johnroe"]`,
+	})
+
+	llm := &fakeLLM{}
+	result, err := Consolidate(ConsolidateOptions{LLM: llm, InputDir: dir, Format: "jsonl"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(llm.prompts) != 0 {
+		t.Errorf("LLM called %d times, want 0 for extracted JSON", len(llm.prompts))
+	}
+	if len(result.Applicants) != 1 || result.Applicants[0].SourceFile != "john_roe.json" {
+		t.Fatalf("applicants = %+v", result.Applicants)
+	}
+
+	var record ApplicantRecord
+	if err := json.Unmarshal([]byte(strings.TrimSpace(string(result.Table))), &record); err != nil {
+		t.Fatal(err)
+	}
+	if record.Name != "John Roe" || record.YearsOfExp == nil || *record.YearsOfExp != 5.5 || record.Email != "" {
+		t.Errorf("record = %+v", record)
+	}
+}
+
+func TestConsolidateBlindSummaries(t *testing.T) {
+	dir := t.TempDir()
+	blind, err := LoadBlindMapping(nil, filepath.Join(dir, "blind-map.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := blind.idFor("jane_doe", "Jane Doe")
+	if !isBlindID(id) {
+		t.Fatalf("idFor() = %q, want an anonymous ID", id)
+	}
+	writeFiles(t, dir, map[string]string{
+		"summaries/" + id + "_summary.txt": id + " is a senior backend engineer.",
+		"txts/jane_doe.txt":                sourceText,
+	})
+
+	llm := &fakeLLM{respond: func(string) string {
+		return strings.Replace(extractionResponse, "Jane Doe", id, 1)
+	}}
+
+	t.Run("without mapping", func(t *testing.T) {
+		opts := ConsolidateOptions{LLM: llm, InputDir: filepath.Join(dir, "summaries"), SourceDir: filepath.Join(dir, "txts")}
+		warned := warnings(&opts.Env)
+		result, err := Consolidate(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The anonymous ID has no source text of its own, which must not be reported
+		if len(*warned) != 0 {
+			t.Errorf("warnings = %q, want none", *warned)
+		}
+		if got := result.Applicants[0]; got.Name != id || HasValue(got.Email) {
+			t.Errorf("applicant = %+v, want the anonymous ID without contact details", got)
+		}
+	})
+
+	t.Run("with mapping", func(t *testing.T) {
+		opts := ConsolidateOptions{LLM: llm, InputDir: filepath.Join(dir, "summaries"), SourceDir: filepath.Join(dir, "txts"), Blind: blind}
+		warned := warnings(&opts.Env)
+		result, err := Consolidate(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := result.Applicants[0]
+		if got.Name != id || got.SourceFile != id || HasValue(got.Email) || HasValue(got.CVLink) {
+			t.Errorf("applicant = %+v, want the anonymous ID without contact details", got)
+		}
+		// The original text was found through the mapping and the made-up skill was flagged
+		if len(*warned) != 1 || !strings.Contains((*warned)[0], "skill: Rust") {
+			t.Errorf("warnings = %q, want the unverified skill", *warned)
+		}
+	})
+}
+
+func TestConsolidateErrors(t *testing.T) {
+	if _, err := Consolidate(ConsolidateOptions{}); err == nil {
+		t.Error("expected an error without an input folder")
+	}
+	if _, err := Consolidate(ConsolidateOptions{FromStore: true}); err == nil {
+		t.Error("expected an error reading from the store without a store")
+	}
+	if _, err := Consolidate(ConsolidateOptions{InputDir: t.TempDir(), Format: "pdf"}); err == nil {
+		t.Error("expected an error for an unsupported format")
+	}
+}
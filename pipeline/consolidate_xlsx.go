@@ -0,0 +1,167 @@
+package pipeline
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+const xlsxSheetName = "Applicants"
+
+// xlsxColumn describes one column of the consolidated workbook
+type xlsxColumn struct {
+	header string
+	width  float64
+	value  func(ApplicantInfo) any
+}
+
+var xlsxColumns = []xlsxColumn{
+	{"Applicant", 24, func(a ApplicantInfo) any { return a.Name }},
+	{"Role", 22, func(a ApplicantInfo) any { return a.Role }},
+	{"Seniority", 12, func(a ApplicantInfo) any { return a.Seniority }},
+	{"Status", 16, func(a ApplicantInfo) any { return a.Status }},
+	{"Current Position", 24, func(a ApplicantInfo) any { return a.CurrentPosition }},
+	{"Current Company", 22, func(a ApplicantInfo) any { return a.CurrentCompany }},
+	{"Years of Exp", 12, func(a ApplicantInfo) any {
+		// Numbers let recruiters sort and filter the column properly
+		if years := ParseYearsOfExp(a.YearsOfExp); years != nil {
+			return *years
+		}
+		return a.YearsOfExp
+	}},
+	{"CV Link", 30, func(a ApplicantInfo) any { return a.CVLink }},
+	{"Skillset", 40, func(a ApplicantInfo) any { return a.Skillset }},
+	{"Remarks", 50, func(a ApplicantInfo) any { return a.Remarks }},
+	{"Email", 28, func(a ApplicantInfo) any { return a.Email }},
+	{"Phone", 18, func(a ApplicantInfo) any { return a.Phone }},
+	{"Location", 20, func(a ApplicantInfo) any { return a.Location }},
+	{"LinkedIn", 30, func(a ApplicantInfo) any { return a.LinkedIn }},
+	{"GitHub", 30, func(a ApplicantInfo) any { return a.GitHub }},
+	{"Portfolio", 30, func(a ApplicantInfo) any { return a.Portfolio }},
+	{"Unverified", 30, func(a ApplicantInfo) any { return strings.Join(a.Unverified, "\n") }},
+}
+
+// generateConsolidatedXLSX renders the applicants as an Excel workbook with a frozen,
+// filterable header row, wrapped text and clickable CV, profile and email links
+func generateConsolidatedXLSX(applicants []ApplicantInfo) ([]byte, error) {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	if err := f.SetSheetName(f.GetSheetName(0), xlsxSheetName); err != nil {
+		return nil, fmt.Errorf("failed to rename sheet: %w", err)
+	}
+
+	headerStyle, err := f.NewStyle(&excelize.Style{
+		Font:      &excelize.Font{Bold: true},
+		Fill:      excelize.Fill{Type: "pattern", Color: []string{"#D9E1F2"}, Pattern: 1},
+		Alignment: &excelize.Alignment{Vertical: "center", WrapText: true},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create header style: %w", err)
+	}
+	cellStyle, err := f.NewStyle(&excelize.Style{
+		Alignment: &excelize.Alignment{Vertical: "top", WrapText: true},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cell style: %w", err)
+	}
+	linkStyle, err := f.NewStyle(&excelize.Style{
+		Font:      &excelize.Font{Color: "#0563C1", Underline: "single"},
+		Alignment: &excelize.Alignment{Vertical: "top", WrapText: true},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create link style: %w", err)
+	}
+
+	for col, column := range xlsxColumns {
+		name, err := excelize.ColumnNumberToName(col + 1)
+		if err != nil {
+			return nil, err
+		}
+		if err := f.SetColWidth(xlsxSheetName, name, name, column.width); err != nil {
+			return nil, fmt.Errorf("failed to set column width: %w", err)
+		}
+		if err := f.SetCellValue(xlsxSheetName, name+"1", column.header); err != nil {
+			return nil, fmt.Errorf("failed to write header: %w", err)
+		}
+	}
+
+	lastCol, err := excelize.ColumnNumberToName(len(xlsxColumns))
+	if err != nil {
+		return nil, err
+	}
+	if err := f.SetCellStyle(xlsxSheetName, "A1", lastCol+"1", headerStyle); err != nil {
+		return nil, fmt.Errorf("failed to style header: %w", err)
+	}
+
+	for i, applicant := range applicants {
+		row := i + 2
+		for col, column := range xlsxColumns {
+			cell, err := excelize.CoordinatesToCellName(col+1, row)
+			if err != nil {
+				return nil, err
+			}
+			if err := f.SetCellValue(xlsxSheetName, cell, column.value(applicant)); err != nil {
+				return nil, fmt.Errorf("failed to write cell %s: %w", cell, err)
+			}
+
+			style := cellStyle
+			if link := xlsxCellLink(column.header, applicant); HasValue(link) {
+				if err := f.SetCellHyperLink(xlsxSheetName, cell, link, "External"); err != nil {
+					return nil, fmt.Errorf("failed to add link to %s: %w", cell, err)
+				}
+				style = linkStyle
+			}
+			if err := f.SetCellStyle(xlsxSheetName, cell, cell, style); err != nil {
+				return nil, fmt.Errorf("failed to style cell %s: %w", cell, err)
+			}
+		}
+	}
+
+	if err := f.SetPanes(xlsxSheetName, &excelize.Panes{
+		Freeze:      true,
+		YSplit:      1,
+		TopLeftCell: "A2",
+		ActivePane:  "bottomLeft",
+	}); err != nil {
+		return nil, fmt.Errorf("failed to freeze header row: %w", err)
+	}
+
+	lastRow := len(applicants) + 1
+	if err := f.AutoFilter(xlsxSheetName, fmt.Sprintf("A1:%s%d", lastCol, lastRow), nil); err != nil {
+		return nil, fmt.Errorf("failed to add autofilter: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		return nil, fmt.Errorf("failed to encode workbook: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// xlsxCellLink returns the hyperlink target for columns that hold links, or "" for all others
+func xlsxCellLink(header string, applicant ApplicantInfo) string {
+	switch header {
+	case "CV Link":
+		return applicant.CVLink
+	case "LinkedIn":
+		return applicant.LinkedIn
+	case "GitHub":
+		return applicant.GitHub
+	case "Portfolio":
+		return applicant.Portfolio
+	case "Email":
+		if HasValue(applicant.Email) {
+			return "mailto:" + applicant.Email
+		}
+	}
+	return ""
+}
+
+// HasValue reports whether a field holds an actual value rather than the "N/A" placeholder
+func HasValue(value string) bool {
+	value = strings.TrimSpace(value)
+	return value != "" && !strings.EqualFold(value, "N/A")
+}
@@ -0,0 +1,246 @@
+package pipeline
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/nicoalimin/resume-analyzer/interfaces"
+	"github.com/nicoalimin/resume-analyzer/prompts"
+)
+
+// EmailPattern matches email addresses in resume text
+var EmailPattern = regexp.MustCompile(`(?i)[a-z0-9._%+\-]+@[a-z0-9.\-]+\.[a-z]{2,}`)
+
+// PhonePattern matches phone number candidates in resume text. Matches that are year
+// ranges (see YearRangePattern), dates or ID numbers are not phone numbers.
+var PhonePattern = regexp.MustCompile(`\(?\+?\d[\d\s().\-]{6,}\d`)
+
+// YearRangePattern matches employment periods such as "2019 - 2021" that look like phone numbers
+var YearRangePattern = regexp.MustCompile(`^(19|20)\d\d\s*[-–.]\s*(19|20)\d\d$`)
+
+// datePattern matches dates such as "01.01.2019" or "2019-01-15"
+var datePattern = regexp.MustCompile(`^(?:\d{1,2}[./\-]\d{1,2}[./\-](?:19|20)\d\d|(?:19|20)\d\d[./\-]\d{1,2}[./\-]\d{1,2})$`)
+
+// idNumberPattern matches ID and card numbers such as "1234-5678-9012": groups of four digits
+// without a trunk prefix, or one long run of digits
+var idNumberPattern = regexp.MustCompile(`^(?:[1-9]\d{3}(?:[\s\-]\d{4}){2,}|\d{13,})$`)
+
+var linkedInPattern = regexp.MustCompile(`(?i)linkedin\.com/in/([a-z0-9\-_%]+)`)
+var gitHubPattern = regexp.MustCompile(`(?i)github\.com/([a-z0-9](?:[a-z0-9\-]{0,38}))`)
+
+// gitHubReservedPaths are github.com paths that are not user profiles
+var gitHubReservedPaths = map[string]bool{
+	"about": true, "features": true, "orgs": true, "topics": true, "pricing": true,
+	"sponsors": true, "login": true, "join": true, "marketplace": true, "settings": true,
+}
+
+// applyContactDetails fills the contact fields of an applicant from the resume text and the
+// PDF hyperlinks. Hyperlink targets are exact and win over values found by pattern matching,
+// which in turn win over the applicant's own values. Values already on the applicant are kept
+// when they pass the same validation. The LLM, if one is given, is only asked when a field
+// that pattern matching can find (email, phone, LinkedIn, GitHub) is still missing; the call
+// then fills every gap, but only with values that appear in the text. An error is returned
+// if the LLM call fails; the gaps are left as "N/A".
+func applyContactDetails(ctx context.Context, applicant *ApplicantInfo, text string, links []string, llm interfaces.LLMService, countryCode string) error {
+	found := findContactDetails(text, countryCode)
+	linked := linkDetails(links, countryCode)
+	found.Email = cmp.Or(linked.Email, found.Email)
+	found.Phone = cmp.Or(linked.Phone, found.Phone)
+	found.LinkedIn = cmp.Or(linked.LinkedIn, found.LinkedIn)
+	found.GitHub = cmp.Or(linked.GitHub, found.GitHub)
+	normalizePhoneNumber := func(value string) string {
+		return normalizePhone(value, countryCode)
+	}
+	inText := contactEvidence(normalizeText(text))
+
+	fields := []struct {
+		target    *string
+		found     string
+		normalize func(string) string
+		// matched is set for fields that findContactDetails looks for; the LLM is not asked
+		// for a field only because the resume text has no pattern for it
+		matched bool
+		// inText reports whether a normalized answer of the LLM appears in the resume text
+		inText func(string) bool
+	}{
+		{&applicant.Email, found.Email, NormalizeEmail, true, inText.contains},
+		{&applicant.Phone, found.Phone, normalizePhoneNumber, true, inText.phone},
+		{&applicant.Location, found.Location, normalizeLocation, false, inText.contains},
+		{&applicant.LinkedIn, found.LinkedIn, normalizeLinkedIn, true, inText.profile},
+		{&applicant.GitHub, found.GitHub, normalizeGitHub, true, inText.profile},
+	}
+
+	missing := false
+	for _, field := range fields {
+		switch {
+		case field.found != "":
+			*field.target = field.found
+		case field.normalize(*field.target) != "":
+			*field.target = field.normalize(*field.target)
+		default:
+			*field.target = ""
+			missing = missing || field.matched
+		}
+	}
+
+	var fallbackErr error
+	if missing && llm != nil {
+		response, err := interfaces.GenerateTextContext(ctx, llm, prompts.GetContactExtractionPrompt(text))
+		if err != nil {
+			fallbackErr = fmt.Errorf("contact extraction fallback failed: %w", err)
+		} else {
+			answer := parseJSONFields(response)
+			for i, key := range []string{"email", "phone", "location", "linkedin", "github"} {
+				// The model only reads the text, so a value that is not in it was made up
+				if value := fields[i].normalize(answer[key]); *fields[i].target == "" && value != "" && fields[i].inText(value) {
+					*fields[i].target = value
+				}
+			}
+		}
+	}
+
+	for _, field := range fields {
+		if *field.target == "" {
+			*field.target = "N/A"
+		}
+	}
+	return fallbackErr
+}
+
+// contactEvidence is the resume text prepared for checking the contact details the LLM returns
+type contactEvidence string
+
+// contains reports whether value appears in the text, ignoring case and whitespace
+func (e contactEvidence) contains(value string) bool {
+	return value != "" && strings.Contains(string(e), normalizeText(value))
+}
+
+// phone reports whether the subscriber number (the last eight digits) of a normalized phone
+// number appears in the text, however it is formatted there
+func (e contactEvidence) phone(value string) bool {
+	digits := OnlyDigits(value)
+	return len(digits) >= 8 && strings.Contains(OnlyDigits(string(e)), digits[len(digits)-8:])
+}
+
+// profile reports whether the user name of a canonical profile URL appears in the text
+func (e contactEvidence) profile(value string) bool {
+	return e.contains(value[strings.LastIndex(value, "/")+1:])
+}
+
+// normalizeText lowercases value and collapses its whitespace
+func normalizeText(value string) string {
+	return strings.ToLower(strings.Join(strings.Fields(value), " "))
+}
+
+// findContactDetails extracts contact details from text using patterns only
+func findContactDetails(text string, countryCode string) ApplicantInfo {
+	var details ApplicantInfo
+
+	for _, match := range EmailPattern.FindAllString(text, -1) {
+		if email := NormalizeEmail(match); email != "" {
+			details.Email = email
+			break
+		}
+	}
+	for _, match := range PhonePattern.FindAllString(text, -1) {
+		if phone := normalizePhone(match, countryCode); phone != "" {
+			details.Phone = phone
+			break
+		}
+	}
+	if match := linkedInPattern.FindString(text); match != "" {
+		details.LinkedIn = normalizeLinkedIn(match)
+	}
+	for _, match := range gitHubPattern.FindAllString(text, -1) {
+		if profile := normalizeGitHub(match); profile != "" {
+			details.GitHub = profile
+			break
+		}
+	}
+	return details
+}
+
+// NormalizeEmail lowercases a valid email address, or returns "" if value is not one
+func NormalizeEmail(value string) string {
+	value = strings.TrimSpace(value)
+	if match := EmailPattern.FindString(value); match != "" && match == value {
+		return strings.ToLower(value)
+	}
+	return ""
+}
+
+// normalizePhone converts a phone number to E.164 (+6591234567), or returns "" if it is not one.
+// Numbers without an international prefix need a default country code; without one their
+// country is unknown, so they are kept as written.
+func normalizePhone(value string, countryCode string) string {
+	value = strings.TrimSpace(value)
+	// The pattern can take in the brackets around a period, e.g. "(2019-2021)"
+	bare := strings.Trim(value, "()[] ")
+	if bare == "" || YearRangePattern.MatchString(bare) || datePattern.MatchString(bare) || idNumberPattern.MatchString(bare) {
+		return ""
+	}
+
+	digits := OnlyDigits(value)
+	firstDigit := strings.IndexAny(value, "0123456789")
+	switch {
+	case firstDigit > 0 && strings.Contains(value[:firstDigit], "+"):
+	case strings.HasPrefix(digits, "00"):
+		digits = digits[2:]
+	default:
+		countryCode = OnlyDigits(countryCode)
+		if countryCode == "" {
+			if len(digits) < 8 || len(digits) > 15 {
+				return ""
+			}
+			return strings.Join(strings.Fields(value), " ")
+		}
+		// Drop the national trunk prefix, e.g. 020 7946 0000 -> +44 20 7946 0000
+		digits = countryCode + strings.TrimLeft(digits, "0")
+	}
+
+	if len(digits) < 8 || len(digits) > 15 || digits[0] == '0' {
+		return ""
+	}
+	return "+" + digits
+}
+
+// normalizeLocation accepts any short free-text location
+func normalizeLocation(value string) string {
+	value = strings.TrimSpace(value)
+	if !HasValue(value) || len(value) > 100 {
+		return ""
+	}
+	return value
+}
+
+// normalizeLinkedIn returns the canonical profile URL for a LinkedIn link, or ""
+func normalizeLinkedIn(value string) string {
+	match := linkedInPattern.FindStringSubmatch(value)
+	if match == nil {
+		return ""
+	}
+	return "https://www.linkedin.com/in/" + strings.ToLower(strings.TrimRight(match[1], "-_"))
+}
+
+// normalizeGitHub returns the canonical profile URL for a GitHub link, or ""
+func normalizeGitHub(value string) string {
+	match := gitHubPattern.FindStringSubmatch(value)
+	if match == nil || gitHubReservedPaths[strings.ToLower(match[1])] {
+		return ""
+	}
+	return "https://github.com/" + strings.ToLower(match[1])
+}
+
+// OnlyDigits returns the digits of value, e.g. to compare phone numbers in different formats
+func OnlyDigits(value string) string {
+	var digits strings.Builder
+	for _, r := range value {
+		if r >= '0' && r <= '9' {
+			digits.WriteRune(r)
+		}
+	}
+	return digits.String()
+}
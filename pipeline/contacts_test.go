@@ -0,0 +1,168 @@
+package pipeline
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestNormalizePhone(t *testing.T) {
+	tests := []struct {
+		value       string
+		countryCode string
+		want        string
+	}{
+		{"+65 9123 4567", "", "+6591234567"},
+		{"(+65) 9123-4567", "44", "+6591234567"},
+		{"0065 9123 4567", "", "+6591234567"},
+		{"020 7946 0000", "44", "+442079460000"},
+		{"9123 4567", "+65", "+6591234567"},
+		// Without a country code the country is unknown, so local numbers are kept as written
+		{"020  7946 0000", "", "020 7946 0000"},
+		{"2019 - 2021", "65", ""},
+		{"(2019-2021)", "65", ""},
+		{"(2019-2021", "65", ""},
+		{"01.01.2019", "65", ""},
+		{"2019-01-15", "65", ""},
+		{"1234-5678-9012", "65", ""},
+		{"1234567890123", "65", ""},
+		{"0812-3456-7890", "62", "+6281234567890"},
+		{"12345", "65", ""},
+		{"N/A", "65", ""},
+	}
+	for _, test := range tests {
+		if got := normalizePhone(test.value, test.countryCode); got != test.want {
+			t.Errorf("normalizePhone(%q, %q) = %q, want %q", test.value, test.countryCode, got, test.want)
+		}
+	}
+}
+
+func TestNormalizeProfiles(t *testing.T) {
+	tests := []struct {
+		normalize func(string) string
+		value     string
+		want      string
+	}{
+		{NormalizeEmail, " Jane.Doe@Example.COM ", "jane.doe@example.com"},
+		{NormalizeEmail, "jane at example.com", ""},
+		{normalizeLinkedIn, "http://linkedin.com/in/Jane-Doe-", "https://www.linkedin.com/in/jane-doe"},
+		{normalizeLinkedIn, "https://linkedin.com/company/acme", ""},
+		{normalizeGitHub, "github.com/JaneDoe/resume-analyzer", "https://github.com/janedoe"},
+		{normalizeGitHub, "https://github.com/features", ""},
+	}
+	for _, test := range tests {
+		if got := test.normalize(test.value); got != test.want {
+			t.Errorf("normalize(%q) = %q, want %q", test.value, got, test.want)
+		}
+	}
+}
+
+func TestApplyLinksPortfolio(t *testing.T) {
+	tests := []struct {
+		links []string
+		want  string
+	}{
+		{[]string{"https://www.acme.com", "https://nus.edu.sg"}, "N/A"},
+		{[]string{"https://www.acme.com", "https://www.behance.net/janedoe"}, "https://www.behance.net/janedoe"},
+		{[]string{"http://janedoe.github.io", "https://dribbble.com/janedoe"}, "http://janedoe.github.io"},
+		{[]string{"ftp://files.github.io/cv.pdf"}, "N/A"},
+	}
+	for _, test := range tests {
+		applicant := ApplicantInfo{}
+		applyLinks(&applicant, test.links, "")
+		if applicant.Portfolio != test.want {
+			t.Errorf("portfolio of %q = %q, want %q", test.links, applicant.Portfolio, test.want)
+		}
+	}
+}
+
+func TestApplyContactDetails(t *testing.T) {
+	text := `Jane Doe
+Singapore
+ID 1234-5678-9012, born 01.01.1990
+Worked at Acme (2019-2021)
+jane.doe@example.com | +65 9123 4567
+linkedin.com/in/janedoe | github.com/janedoe`
+
+	t.Run("patterns only", func(t *testing.T) {
+		// Location has no pattern and must not cause a fallback call on its own
+		llm := &fakeLLM{}
+		applicant := ApplicantInfo{Email: "old@example.com", Location: "N/A"}
+		if err := applyContactDetails(context.Background(), &applicant, text, nil, llm, "65"); err != nil {
+			t.Fatal(err)
+		}
+		if len(llm.prompts) != 0 {
+			t.Errorf("LLM called %d times, want 0", len(llm.prompts))
+		}
+		want := ApplicantInfo{
+			Email:    "jane.doe@example.com",
+			Phone:    "+6591234567",
+			Location: "N/A",
+			LinkedIn: "https://www.linkedin.com/in/janedoe",
+			GitHub:   "https://github.com/janedoe",
+		}
+		if !reflect.DeepEqual(applicant, want) {
+			t.Errorf("applicant = %+v, want %+v", applicant, want)
+		}
+	})
+
+	t.Run("hyperlinks win over the text", func(t *testing.T) {
+		links := []string{
+			"https://www.acme.com/careers",
+			"https://linkedin.com/in/jane-doe-sg",
+			"mailto:jane@janedoe.io?subject=Hello",
+			"https://janedoe.github.io/",
+		}
+		applicant := ApplicantInfo{LinkedIn: "https://www.linkedin.com/in/someone-else"}
+		applyLinks(&applicant, links, "65")
+		if err := applyContactDetails(context.Background(), &applicant, text, links, nil, "65"); err != nil {
+			t.Fatal(err)
+		}
+		want := ApplicantInfo{
+			Email:     "jane@janedoe.io",
+			Phone:     "+6591234567",
+			Location:  "N/A",
+			LinkedIn:  "https://www.linkedin.com/in/jane-doe-sg",
+			GitHub:    "https://github.com/janedoe",
+			Portfolio: "https://janedoe.github.io/",
+		}
+		if !reflect.DeepEqual(applicant, want) {
+			t.Errorf("applicant = %+v, want %+v", applicant, want)
+		}
+	})
+
+	t.Run("fallback fills the gaps from the text", func(t *testing.T) {
+		llm := &fakeLLM{respond: func(string) string {
+			return `{"email": "jane@acme.com", "phone": "N/A", "location": "Singapore", "linkedin": "linkedin.com/in/janedoe", "github": "https://github.com/jdoe"}`
+		}}
+		applicant := ApplicantInfo{}
+		text := "Jane Doe, Singapore\nHP: +65 9123 4567\nLinkedIn: JaneDoe"
+		if err := applyContactDetails(context.Background(), &applicant, text, nil, llm, ""); err != nil {
+			t.Fatal(err)
+		}
+		if len(llm.prompts) != 1 {
+			t.Fatalf("LLM called %d times, want 1", len(llm.prompts))
+		}
+		// The email and GitHub profile are not in the text and are rejected
+		want := ApplicantInfo{
+			Email:    "N/A",
+			Phone:    "+6591234567",
+			Location: "Singapore",
+			LinkedIn: "https://www.linkedin.com/in/janedoe",
+			GitHub:   "N/A",
+		}
+		if !reflect.DeepEqual(applicant, want) {
+			t.Errorf("applicant = %+v, want %+v", applicant, want)
+		}
+	})
+
+	t.Run("failed fallback", func(t *testing.T) {
+		applicant := ApplicantInfo{}
+		if err := applyContactDetails(context.Background(), &applicant, "Jane Doe", nil, &fakeLLM{}, ""); err == nil {
+			t.Error("expected the fallback error")
+		}
+		if applicant.Email != "N/A" || applicant.Phone != "N/A" {
+			t.Errorf("applicant = %+v, want N/A for missing values", applicant)
+		}
+	})
+}
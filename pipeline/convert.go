@@ -0,0 +1,96 @@
+package pipeline
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nicoalimin/resume-analyzer/interfaces"
+	"github.com/nicoalimin/resume-analyzer/modules/pdf/links"
+)
+
+// ConvertOptions configures Convert
+type ConvertOptions struct {
+	Env
+	// OCR extracts the text from each PDF
+	OCR interfaces.OCRService
+	// InputDir is the folder containing the PDFs
+	InputDir string
+	// OutputDir is the folder the text and hyperlink files are written to
+	OutputDir string
+}
+
+// ConvertedDocument is a PDF that was converted to text
+type ConvertedDocument struct {
+	Key      string
+	PDFPath  string
+	TextPath string
+	Text     string
+	Links    []string
+}
+
+// ConvertResult is the result of Convert
+type ConvertResult struct {
+	Documents []ConvertedDocument
+	Failed    []FileError
+}
+
+// Convert extracts the text of every PDF in InputDir with the OCR service and saves it to
+// OutputDir as <name>.txt. The targets of hyperlinks in each PDF are saved next to the
+// text as <name>.links.json.
+func Convert(opts ConvertOptions) (*ConvertResult, error) {
+	if opts.InputDir == "" || opts.OutputDir == "" {
+		return nil, errors.New("both an input and an output folder must be specified")
+	}
+	if opts.OCR == nil {
+		return nil, errors.New("no OCR service given")
+	}
+
+	files, err := os.ReadDir(opts.InputDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read input directory: %w", err)
+	}
+
+	result := &ConvertResult{}
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".pdf") {
+			continue
+		}
+		baseName := strings.TrimSuffix(file.Name(), ".pdf")
+		pdfPath := filepath.Join(opts.InputDir, file.Name())
+		outputPath := filepath.Join(opts.OutputDir, baseName+".txt")
+
+		opts.started(file.Name())
+		extractedText, err := opts.OCR.ExtractTextFromPDF(pdfPath)
+		if err != nil {
+			result.Failed = append(result.Failed, opts.fail(file.Name(), fmt.Errorf("textract failed for %s: %w", file.Name(), err)))
+			continue
+		}
+
+		if err := opts.writeFile(outputPath, []byte(extractedText), 0644); err != nil {
+			opts.warn(file.Name(), fmt.Errorf("failed to write output for %s: %w", file.Name(), err))
+		} else {
+			opts.saved(file.Name(), outputPath)
+		}
+
+		// Hyperlinks are not part of the visible text, so they are read from the PDF annotations
+		pdfLinks, err := links.ExtractLinks(pdfPath)
+		if err != nil {
+			opts.warn(file.Name(), fmt.Errorf("failed to read links from %s: %w", file.Name(), err))
+		} else if err := opts.writeLinksFile(opts.OutputDir, baseName, pdfLinks); err != nil {
+			opts.warn(file.Name(), fmt.Errorf("failed to write links for %s: %w", file.Name(), err))
+		}
+
+		opts.storeDocument(baseName, pdfPath, extractedText, pdfLinks)
+		result.Documents = append(result.Documents, ConvertedDocument{
+			Key:      baseName,
+			PDFPath:  pdfPath,
+			TextPath: outputPath,
+			Text:     extractedText,
+			Links:    pdfLinks,
+		})
+	}
+	return result, nil
+}
@@ -0,0 +1,54 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/nicoalimin/resume-analyzer/interfaces"
+)
+
+// DocumentOptions configures ExtractDocument
+type DocumentOptions struct {
+	Env
+	// LLM extracts the structured fields
+	LLM interfaces.LLMService
+	// ContactFallback asks the LLM for contact details that pattern matching did not find
+	ContactFallback bool
+}
+
+// ExtractDocument extracts the applicant information of a single resume from its OCR text and
+// PDF hyperlinks, the way Extract and Consolidate do for a folder. Profile links and contact
+// details found by pattern matching take precedence over the model's answer, and values that
+// cannot be found in the text are listed in Unverified and reported as warnings. key names the
+// candidate in progress events and the candidate store, and is used as the name if the model
+// finds none.
+func ExtractDocument(opts DocumentOptions, key string, text string, links []string) (ApplicantInfo, error) {
+	if opts.LLM == nil {
+		return ApplicantInfo{}, errors.New("no LLM service given")
+	}
+
+	applicant, err := opts.extractText(key, text, opts.LLM)
+	if err != nil {
+		return ApplicantInfo{}, fmt.Errorf("extraction failed: %w", err)
+	}
+
+	applyLinks(&applicant, links, opts.PhoneCountryCode)
+	ctx := context.Background()
+	var fallback interfaces.LLMService
+	if opts.ContactFallback {
+		fallback = opts.LLM
+		ctx = opts.call([]string{key}, text)
+	}
+	if err := applyContactDetails(ctx, &applicant, text, links, fallback, opts.PhoneCountryCode); err != nil {
+		opts.warn(key, err)
+	}
+
+	applicant.Unverified = verifyApplicantInfo(applicant, text)
+	for _, value := range applicant.Unverified {
+		opts.warn(key, fmt.Errorf("unverified value: %s", value))
+	}
+
+	opts.storeExtraction(key, applicant)
+	return applicant, nil
+}
@@ -0,0 +1,190 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nicoalimin/resume-analyzer/interfaces"
+	"github.com/nicoalimin/resume-analyzer/prompts"
+)
+
+// ExtractOptions configures Extract
+type ExtractOptions struct {
+	Env
+	// LLM extracts the structured fields
+	LLM interfaces.LLMService
+	// InputDir is the folder containing the .txt and .links.json files written by Convert
+	InputDir string
+	// OutputDir is the folder the extracted JSON files are written to
+	OutputDir string
+}
+
+// ExtractedApplicant is the structured data extracted for one candidate
+type ExtractedApplicant struct {
+	Key       string
+	Path      string
+	Applicant ApplicantInfo
+}
+
+// ExtractResult is the result of Extract
+type ExtractResult struct {
+	Applicants []ExtractedApplicant
+	Failed     []FileError
+}
+
+// Extract sends the OCR text of every .txt file in InputDir straight to the LLM together
+// with the extraction schema and saves one JSON file per candidate to OutputDir. The
+// output folder can be passed to Consolidate in place of a summaries folder.
+func Extract(opts ExtractOptions) (*ExtractResult, error) {
+	if opts.InputDir == "" || opts.OutputDir == "" {
+		return nil, errors.New("both an input and an output folder must be specified")
+	}
+	if opts.LLM == nil {
+		return nil, errors.New("no LLM service given")
+	}
+
+	files, err := os.ReadDir(opts.InputDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read input directory: %w", err)
+	}
+
+	result := &ExtractResult{}
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".txt") {
+			continue
+		}
+
+		baseName := strings.TrimSuffix(file.Name(), ".txt")
+		inputPath := filepath.Join(opts.InputDir, file.Name())
+		outputPath := filepath.Join(opts.OutputDir, baseName+".json")
+
+		opts.started(file.Name())
+
+		// Read the text file
+		content, err := opts.readFile(inputPath)
+		if err != nil {
+			result.Failed = append(result.Failed, opts.fail(file.Name(), fmt.Errorf("failed to read %s: %w", file.Name(), err)))
+			continue
+		}
+
+		applicant, err := opts.extractText(baseName, string(content), opts.LLM)
+		if err != nil {
+			result.Failed = append(result.Failed, opts.fail(file.Name(), fmt.Errorf("bedrock failed for %s: %w", file.Name(), err)))
+			continue
+		}
+
+		// Hyperlinks and contact details found by pattern matching take precedence over the model's answer
+		pdfLinks, err := opts.readLinksFile(opts.InputDir, baseName)
+		if err != nil {
+			opts.warn(file.Name(), fmt.Errorf("failed to read links for %s: %w", file.Name(), err))
+		}
+		applyLinks(&applicant, pdfLinks, opts.PhoneCountryCode)
+		applyContactDetails(context.Background(), &applicant, string(content), pdfLinks, nil, opts.PhoneCountryCode)
+
+		data, err := json.MarshalIndent(applicant, "", "  ")
+		if err != nil {
+			result.Failed = append(result.Failed, opts.fail(file.Name(), fmt.Errorf("failed to encode result for %s: %w", file.Name(), err)))
+			continue
+		}
+
+		if err := opts.writeFile(outputPath, data, 0644); err != nil {
+			opts.warn(file.Name(), fmt.Errorf("failed to write result for %s: %w", file.Name(), err))
+		} else {
+			opts.saved(file.Name(), outputPath)
+		}
+		opts.storeExtraction(baseName, applicant)
+		result.Applicants = append(result.Applicants, ExtractedApplicant{Key: baseName, Path: outputPath, Applicant: applicant})
+	}
+	return result, nil
+}
+
+// extractText extracts structured information from resume text in a single LLM call.
+// Contact details found by pattern matching are applied by the caller.
+func (e *Env) extractText(key string, text string, llm interfaces.LLMService) (ApplicantInfo, error) {
+	ctx := e.call([]string{key}, text)
+	response, err := interfaces.GenerateTextContext(ctx, llm, prompts.GetDirectExtractionPrompt(text))
+	if err != nil {
+		return ApplicantInfo{}, err
+	}
+
+	applicant := ParseApplicantJSON(response)
+	if applicant.Name == "N/A" || applicant.Name == "" {
+		applicant.Name = key
+	}
+	return applicant, nil
+}
+
+// ParseApplicantJSON decodes the JSON object in an LLM response into an ApplicantInfo.
+// If the response is not valid JSON the line-based extractField parser is used instead.
+func ParseApplicantJSON(response string) ApplicantInfo {
+	fields := parseJSONFields(response)
+
+	field := func(name string) string {
+		if value, ok := fields[name]; ok && strings.TrimSpace(value) != "" {
+			return strings.TrimSpace(value)
+		}
+		if len(fields) == 0 {
+			return extractField(response, name)
+		}
+		return "N/A"
+	}
+
+	return ApplicantInfo{
+		Name:            field("name"),
+		Role:            field("role"),
+		Seniority:       field("seniority"),
+		Status:          field("status"),
+		CurrentPosition: field("current_position"),
+		CurrentCompany:  field("current_company"),
+		YearsOfExp:      field("years_of_exp"),
+		CVLink:          field("cv_link"),
+		Skillset:        field("skillset"),
+		Remarks:         field("remarks"),
+		Email:           field("email"),
+		Phone:           field("phone"),
+		Location:        field("location"),
+		LinkedIn:        field("linkedin"),
+		GitHub:          field("github"),
+	}
+}
+
+// parseJSONFields decodes the JSON object in an LLM response into lowercase keys and string values.
+// Models sometimes wrap the object in prose or return numbers and arrays instead of
+// strings, so the object is located first and every value is converted to text.
+// An empty map is returned if no valid object is found.
+func parseJSONFields(response string) map[string]string {
+	fields := map[string]string{}
+
+	start := strings.Index(response, "{")
+	end := strings.LastIndex(response, "}")
+	var raw map[string]any
+	if start != -1 && end > start && json.Unmarshal([]byte(response[start:end+1]), &raw) == nil {
+		for key, value := range raw {
+			fields[strings.ToLower(key)] = stringifyJSONValue(value)
+		}
+	}
+	return fields
+}
+
+// stringifyJSONValue converts a decoded JSON value into the flat string form used by ApplicantInfo
+func stringifyJSONValue(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []any:
+		parts := make([]string, 0, len(v))
+		for _, item := range v {
+			parts = append(parts, stringifyJSONValue(item))
+		}
+		return strings.Join(parts, ", ")
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,52 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/nicoalimin/resume-analyzer/interfaces"
+)
+
+// fakeLLM answers prompts with canned responses and records every call
+type fakeLLM struct {
+	mu sync.Mutex
+	// respond returns the response to a prompt; an empty response fails the call
+	respond func(prompt string) string
+	prompts []string
+	calls   []interfaces.Call
+}
+
+func (f *fakeLLM) GenerateText(prompt string) (string, error) {
+	text, _, err := f.GenerateTextWithUsage(context.Background(), prompt)
+	return text, err
+}
+
+func (f *fakeLLM) GenerateTextWithUsage(ctx context.Context, prompt string) (string, interfaces.TokenUsage, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.prompts = append(f.prompts, prompt)
+	f.calls = append(f.calls, interfaces.CallFrom(ctx))
+	response := ""
+	if f.respond != nil {
+		response = f.respond(prompt)
+	}
+	if response == "" {
+		return "", interfaces.TokenUsage{}, errors.New("no response for prompt")
+	}
+	return response, interfaces.TokenUsage{}, nil
+}
+
+// respondWhen returns a respond function that answers with the first response whose key is
+// contained in the prompt
+func respondWhen(responses map[string]string) func(string) string {
+	return func(prompt string) string {
+		for key, response := range responses {
+			if strings.Contains(prompt, key) {
+				return response
+			}
+		}
+		return ""
+	}
+}
@@ -0,0 +1,129 @@
+package pipeline
+
+import (
+	"cmp"
+	"encoding/json"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// linksFilePath returns the path of the hyperlinks file written next to a candidate's OCR text
+func linksFilePath(dir string, baseName string) string {
+	return filepath.Join(dir, baseName+".links.json")
+}
+
+// writeLinksFile saves the hyperlinks of a PDF as a JSON array
+func (e *Env) writeLinksFile(dir string, baseName string, links []string) error {
+	if links == nil {
+		links = []string{}
+	}
+	data, err := json.MarshalIndent(links, "", "  ")
+	if err != nil {
+		return err
+	}
+	return e.writeFile(linksFilePath(dir, baseName), data, 0644)
+}
+
+// readLinksFile loads the hyperlinks saved by convert-pdfs. A missing file means no links.
+func (e *Env) readLinksFile(dir string, baseName string) ([]string, error) {
+	data, err := e.readFile(linksFilePath(dir, baseName))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var links []string
+	err = json.Unmarshal(data, &links)
+	return links, err
+}
+
+// portfolioHosts are the sites people host portfolios on. Other web links in a resume mostly
+// point to employers, schools or projects, so they are not taken as the portfolio.
+var portfolioHosts = []string{
+	"github.io", "gitlab.io", "behance.net", "dribbble.com", "artstation.com", "about.me",
+	"carrd.co", "notion.site", "wixsite.com", "webflow.io", "netlify.app", "vercel.app",
+}
+
+// linkDetails returns the contact details, profiles and portfolio found in PDF hyperlinks.
+// The first link of each kind is used.
+func linkDetails(links []string, countryCode string) ApplicantInfo {
+	var details ApplicantInfo
+	for _, link := range links {
+		lower := strings.ToLower(link)
+		switch {
+		case normalizeLinkedIn(link) != "":
+			details.LinkedIn = cmp.Or(details.LinkedIn, normalizeLinkedIn(link))
+		case normalizeGitHub(link) != "":
+			details.GitHub = cmp.Or(details.GitHub, normalizeGitHub(link))
+		case strings.HasPrefix(lower, "mailto:"):
+			address, _, _ := strings.Cut(link[len("mailto:"):], "?")
+			details.Email = cmp.Or(details.Email, NormalizeEmail(address))
+		case strings.HasPrefix(lower, "tel:"):
+			details.Phone = cmp.Or(details.Phone, normalizePhone(link[len("tel:"):], countryCode))
+		case isPortfolio(link):
+			details.Portfolio = cmp.Or(details.Portfolio, link)
+		}
+	}
+	return details
+}
+
+// isPortfolio reports whether link is a web page on one of the portfolioHosts
+func isPortfolio(link string) bool {
+	u, err := url.Parse(link)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, portfolioHost := range portfolioHosts {
+		if host == portfolioHost || strings.HasSuffix(host, "."+portfolioHost) {
+			return true
+		}
+	}
+	return false
+}
+
+// applyLinks fills the profile and contact fields of an applicant from PDF hyperlinks.
+// Annotation targets are exact, so they replace extracted values; applyContactDetails keeps
+// them over values it finds in the text as well.
+func applyLinks(applicant *ApplicantInfo, links []string, countryCode string) {
+	linked := linkDetails(links, countryCode)
+	for _, field := range []struct {
+		target *string
+		value  string
+	}{
+		{&applicant.Email, linked.Email},
+		{&applicant.Phone, linked.Phone},
+		{&applicant.LinkedIn, linked.LinkedIn},
+		{&applicant.GitHub, linked.GitHub},
+		{&applicant.Portfolio, linked.Portfolio},
+	} {
+		if field.value != "" {
+			*field.target = field.value
+		}
+	}
+	if !HasValue(applicant.Portfolio) {
+		applicant.Portfolio = "N/A"
+	}
+}
+
+// cvLinkFor returns the link to a candidate's CV: the configured base URL joined with the
+// PDF file name if set, otherwise a file:// URL of the local PDF. The URL is absolute because
+// spreadsheet apps resolve relative links against the workbook, not the working directory.
+func cvLinkFor(baseURL string, baseName string, pdfPath string) string {
+	if baseURL != "" {
+		return strings.TrimRight(baseURL, "/") + "/" + url.PathEscape(baseName+".pdf")
+	}
+	abs, err := filepath.Abs(pdfPath)
+	if err != nil {
+		return pdfPath
+	}
+	path := filepath.ToSlash(abs)
+	if !strings.HasPrefix(path, "/") {
+		// Windows paths such as C:/cv.pdf become file:///C:/cv.pdf
+		path = "/" + path
+	}
+	return (&url.URL{Scheme: "file", Path: path}).String()
+}
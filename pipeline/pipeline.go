@@ -0,0 +1,206 @@
+// Package pipeline runs the stages of the resume analyzer: converting PDFs to text,
+// summarizing, extracting and consolidating applicant information, and querying resumes.
+// Every stage takes an options struct and returns a typed result. Files that fail are
+// skipped and reported in the result; only errors that stop the whole stage are returned.
+package pipeline
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nicoalimin/resume-analyzer/interfaces"
+)
+
+// ApplicantInfo is the structured applicant data shared with the storage layer
+type ApplicantInfo = interfaces.ApplicantInfo
+
+// EventKind is the kind of a progress event
+type EventKind int
+
+const (
+	// EventStarted is sent when a file starts being processed
+	EventStarted EventKind = iota
+	// EventSaved is sent when an output file has been written to Path
+	EventSaved
+	// EventFailed is sent when a file is skipped because of Err
+	EventFailed
+	// EventWarning is sent when something went wrong that did not stop the file, e.g. a
+	// value that could not be verified or a database write that failed
+	EventWarning
+)
+
+// Event reports the progress of a stage
+type Event struct {
+	Kind EventKind
+	File string
+	Path string
+	Err  error
+}
+
+// FileError is a file that was skipped and the reason why
+type FileError struct {
+	File string
+	Err  error
+}
+
+// Error implements the error interface
+func (e FileError) Error() string {
+	return e.File + ": " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e FileError) Unwrap() error {
+	return e.Err
+}
+
+// Env holds the services and settings shared by every stage. All fields are optional.
+type Env struct {
+	// Files reads and writes the pipeline files; plain files are used if nil
+	Files interfaces.FileStore
+	// Store records the documents and results of every stage, if set
+	Store interfaces.CandidateStore
+	// RunID is the run that results are recorded under in Store
+	RunID int64
+	// Campaign is the campaign that candidates are recorded under in Store (default "default")
+	Campaign string
+	// PhoneCountryCode is used to convert phone numbers without an international prefix to E.164
+	PhoneCountryCode string
+	// Progress is called as files are processed
+	Progress func(Event)
+	// Context is passed to every LLM call, described with the candidates whose data it
+	// contains (see interfaces.CallFrom); context.Background() is used if nil
+	Context context.Context
+	// Candidates is called with the keys and texts of the candidates whose data is sent
+	// in the next LLM call, e.g. to report progress
+	Candidates func(keys []string, texts []string)
+}
+
+// osFiles reads and writes plain files
+type osFiles struct{}
+
+func (osFiles) ReadFile(path string) ([]byte, error) {
+	return os.ReadFile(path)
+}
+
+func (osFiles) WriteFile(path string, data []byte, perm os.FileMode) error {
+	return os.WriteFile(path, data, perm)
+}
+
+func (e *Env) files() interfaces.FileStore {
+	if e.Files == nil {
+		return osFiles{}
+	}
+	return e.Files
+}
+
+func (e *Env) readFile(path string) ([]byte, error) {
+	return e.files().ReadFile(path)
+}
+
+func (e *Env) writeFile(path string, data []byte, perm os.FileMode) error {
+	return e.files().WriteFile(path, data, perm)
+}
+
+func (e *Env) progress(event Event) {
+	if e.Progress != nil {
+		e.Progress(event)
+	}
+}
+
+func (e *Env) started(file string) {
+	e.progress(Event{Kind: EventStarted, File: file})
+}
+
+func (e *Env) saved(file string, path string) {
+	e.progress(Event{Kind: EventSaved, File: file, Path: path})
+}
+
+func (e *Env) warn(file string, err error) {
+	e.progress(Event{Kind: EventWarning, File: file, Err: err})
+}
+
+// fail reports a skipped file and returns it for the result
+func (e *Env) fail(file string, err error) FileError {
+	e.progress(Event{Kind: EventFailed, File: file, Err: err})
+	return FileError{File: file, Err: err}
+}
+
+// call returns the context for an LLM call that sends the data of the given candidates
+func (e *Env) call(keys []string, texts ...string) context.Context {
+	if e.Candidates != nil {
+		e.Candidates(keys, texts)
+	}
+	ctx := e.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	// The team of the surrounding call, if any, is kept
+	call := interfaces.CallFrom(ctx)
+	call.Candidates, call.Texts = keys, texts
+	return interfaces.WithCall(ctx, call)
+}
+
+func (e *Env) campaign() string {
+	if e.Campaign == "" {
+		return "default"
+	}
+	return e.Campaign
+}
+
+func (e *Env) candidateRef(key string) interfaces.CandidateRef {
+	return interfaces.CandidateRef{Campaign: e.campaign(), Key: key}
+}
+
+// storeDocument saves a source PDF, its OCR text and hyperlinks to the candidate store, if one is set
+func (e *Env) storeDocument(key string, pdfPath string, text string, links []string) {
+	if e.Store == nil {
+		return
+	}
+	content, err := os.ReadFile(pdfPath)
+	if err != nil {
+		e.warn(filepath.Base(pdfPath), fmt.Errorf("failed to hash %s: %w", pdfPath, err))
+		return
+	}
+	hash := sha256.Sum256(content)
+
+	doc := interfaces.Document{Path: pdfPath, SHA256: hex.EncodeToString(hash[:]), OCRText: text, Links: links}
+	if err := e.Store.SaveDocument(e.RunID, e.candidateRef(key), doc); err != nil {
+		e.warn(key, fmt.Errorf("failed to store document for %s: %w", key, err))
+	}
+}
+
+// storeSummary saves a summary to the candidate store, if one is set
+func (e *Env) storeSummary(key string, summary string) {
+	if e.Store == nil {
+		return
+	}
+	if err := e.Store.SaveSummary(e.RunID, e.candidateRef(key), summary); err != nil {
+		e.warn(key, fmt.Errorf("failed to store summary for %s: %w", key, err))
+	}
+}
+
+// storeExtraction saves extracted applicant information to the candidate store, if one is set
+func (e *Env) storeExtraction(key string, applicant ApplicantInfo) {
+	if e.Store == nil {
+		return
+	}
+	if err := e.Store.SaveExtraction(e.RunID, e.candidateRef(key), applicant); err != nil {
+		e.warn(key, fmt.Errorf("failed to store extracted data for %s: %w", key, err))
+	}
+}
+
+// CandidateBaseName strips the suffixes added by the pipeline stages from a source file name
+func CandidateBaseName(sourceFile string) string {
+	name := filepath.Base(sourceFile)
+	for _, suffix := range []string{"_summary.txt", ".json", ".txt", ".pdf"} {
+		if strings.HasSuffix(name, suffix) {
+			return strings.TrimSuffix(name, suffix)
+		}
+	}
+	return name
+}
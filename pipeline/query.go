@@ -0,0 +1,148 @@
+package pipeline
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nicoalimin/resume-analyzer/interfaces"
+)
+
+// QueryOptions configures Query
+type QueryOptions struct {
+	Env
+	// LLM answers the question
+	LLM interfaces.LLMService
+	// Prompt is the question to ask about the resumes
+	Prompt string
+	// InputDir is the folder containing the .txt files written by Convert
+	InputDir string
+	// Documents are sent instead of the files in InputDir if set
+	Documents []QueryDocument
+	// OutputFile is the file the response is written to; nothing is written if it is empty
+	OutputFile string
+}
+
+// QueryDocument is a resume text passed to Query directly
+type QueryDocument struct {
+	// Name is the name the resume is listed under in the prompt, e.g. its file name
+	Name string
+	Text string
+}
+
+// QueryResult is the result of Query
+type QueryResult struct {
+	Response string
+	// Files are the resume files that were sent with the question
+	Files  []string
+	Failed []FileError
+}
+
+// Query sends all .txt files in InputDir, or the given Documents, to the LLM in a single
+// prompt together with the question
+func Query(opts QueryOptions) (*QueryResult, error) {
+	if opts.Prompt == "" {
+		return nil, errors.New("a prompt must be specified")
+	}
+	if opts.InputDir == "" && len(opts.Documents) == 0 {
+		return nil, errors.New("an input folder must be specified")
+	}
+	if opts.LLM == nil {
+		return nil, errors.New("no LLM service given")
+	}
+
+	result := &QueryResult{}
+	var allTexts []string
+	var keys []string
+	if len(opts.Documents) > 0 {
+		for _, doc := range opts.Documents {
+			allTexts = append(allTexts, doc.Text)
+			result.Files = append(result.Files, doc.Name)
+			keys = append(keys, CandidateBaseName(doc.Name))
+		}
+		return opts.query(result, keys, allTexts)
+	}
+
+	// Read all text files from the input directory
+	files, err := os.ReadDir(opts.InputDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read input directory: %w", err)
+	}
+
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".txt") {
+			continue
+		}
+
+		inputPath := filepath.Join(opts.InputDir, file.Name())
+		opts.started(file.Name())
+
+		// Read the text file
+		content, err := opts.readFile(inputPath)
+		if err != nil {
+			result.Failed = append(result.Failed, opts.fail(file.Name(), fmt.Errorf("failed to read %s: %w", file.Name(), err)))
+			continue
+		}
+
+		allTexts = append(allTexts, string(content))
+		result.Files = append(result.Files, file.Name())
+		keys = append(keys, strings.TrimSuffix(file.Name(), ".txt"))
+	}
+
+	if len(allTexts) == 0 {
+		return nil, errors.New("no .txt files found in the input directory")
+	}
+	return opts.query(result, keys, allTexts)
+}
+
+// query asks the question about the given resume texts and saves the response
+func (o *QueryOptions) query(result *QueryResult, keys []string, allTexts []string) (*QueryResult, error) {
+	// Every resume is sent in this one call
+	ctx := o.call(keys, allTexts...)
+	combinedPrompt := buildCombinedPrompt(o.Prompt, allTexts, result.Files)
+	var err error
+	result.Response, err = interfaces.GenerateTextContext(ctx, o.LLM, combinedPrompt)
+	if err != nil {
+		return nil, fmt.Errorf("bedrock query failed: %w", err)
+	}
+
+	if o.OutputFile != "" {
+		if err := o.writeFile(o.OutputFile, []byte(result.Response), 0644); err != nil {
+			return nil, fmt.Errorf("failed to write response to file: %w", err)
+		}
+	}
+	return result, nil
+}
+
+// buildCombinedPrompt creates a comprehensive prompt combining the user's question with all resume texts
+func buildCombinedPrompt(userPrompt string, texts []string, fileNames []string) string {
+	var prompt strings.Builder
+
+	prompt.WriteString("You are analyzing multiple resumes. Below are the extracted texts from ")
+	prompt.WriteString(fmt.Sprintf("%d resume files.\n\n", len(texts)))
+
+	prompt.WriteString("User Question: ")
+	prompt.WriteString(userPrompt)
+	prompt.WriteString("\n\n")
+
+	prompt.WriteString("Resume Texts:\n")
+	prompt.WriteString(strings.Repeat("=", 50))
+	prompt.WriteString("\n\n")
+
+	for i, text := range texts {
+		prompt.WriteString(fmt.Sprintf("--- Resume %d: %s ---\n", i+1, fileNames[i]))
+		prompt.WriteString(text)
+		prompt.WriteString("\n\n")
+	}
+
+	prompt.WriteString(strings.Repeat("=", 50))
+	prompt.WriteString("\n\n")
+	prompt.WriteString("Please provide a comprehensive answer to the user's question based on the resume texts above. ")
+	prompt.WriteString("If the question requires comparing candidates, please provide detailed analysis and comparisons. ")
+	prompt.WriteString("If the question asks for specific information, please extract and present it clearly.\n\n")
+	prompt.WriteString("Answer:")
+
+	return prompt.String()
+}
@@ -0,0 +1,120 @@
+package pipeline
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nicoalimin/resume-analyzer/interfaces"
+	"github.com/nicoalimin/resume-analyzer/modules/llm/redact"
+	"github.com/nicoalimin/resume-analyzer/prompts"
+)
+
+// SummarizeOptions configures Summarize
+type SummarizeOptions struct {
+	Env
+	// LLM writes the summaries
+	LLM interfaces.LLMService
+	// InputDir is the folder containing the .txt files written by Convert
+	InputDir string
+	// OutputDir is the folder the summaries are written to
+	OutputDir string
+	// Blind, if set, names summaries after anonymous candidate IDs and removes names and
+	// bias-prone attributes from them. New IDs are saved to the mapping. Blind summaries
+	// are not saved to the candidate store.
+	Blind *BlindMapping
+}
+
+// Summary is a summary that was written for a candidate
+type Summary struct {
+	Key string
+	// CandidateID is the anonymous ID the summary is named after in blind mode
+	CandidateID string
+	Path        string
+	Text        string
+}
+
+// SummarizeResult is the result of Summarize
+type SummarizeResult struct {
+	Summaries []Summary
+	Failed    []FileError
+}
+
+// Summarize generates a summary of every .txt file in InputDir and saves it to OutputDir
+// as <name>_summary.txt
+func Summarize(opts SummarizeOptions) (*SummarizeResult, error) {
+	if opts.InputDir == "" || opts.OutputDir == "" {
+		return nil, errors.New("both an input and an output folder must be specified")
+	}
+	if opts.LLM == nil {
+		return nil, errors.New("no LLM service given")
+	}
+
+	files, err := os.ReadDir(opts.InputDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read input directory: %w", err)
+	}
+
+	result := &SummarizeResult{}
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".txt") {
+			continue
+		}
+
+		baseName := strings.TrimSuffix(file.Name(), ".txt")
+		inputPath := filepath.Join(opts.InputDir, file.Name())
+		outputPath := filepath.Join(opts.OutputDir, baseName+"_summary.txt")
+
+		opts.started(file.Name())
+
+		// Read the text file
+		content, err := opts.readFile(inputPath)
+		if err != nil {
+			result.Failed = append(result.Failed, opts.fail(file.Name(), fmt.Errorf("failed to read %s: %w", file.Name(), err)))
+			continue
+		}
+
+		// Generate summary using LLM service
+		ctx := opts.call([]string{baseName}, string(content))
+		prompt := prompts.GetSummaryPrompt(string(content))
+		var candidateID, name string
+		if opts.Blind != nil {
+			// Blind summaries are named after the anonymous ID instead of the original file
+			name = redact.ApplicantName(string(content))
+			candidateID = opts.Blind.idFor(baseName, name)
+			prompt = prompts.GetBlindSummaryPrompt(string(content), candidateID)
+			outputPath = filepath.Join(opts.OutputDir, candidateID+"_summary.txt")
+		}
+		summary, err := interfaces.GenerateTextContext(ctx, opts.LLM, prompt)
+		if err != nil {
+			result.Failed = append(result.Failed, opts.fail(file.Name(), fmt.Errorf("bedrock failed for %s: %w", file.Name(), err)))
+			continue
+		}
+		if opts.Blind != nil {
+			summary = blindText(summary, candidateID, name)
+			if err := opts.Blind.Save(); err != nil {
+				return result, fmt.Errorf("failed to save blind mapping: %w", err)
+			}
+		}
+
+		// Write the summary to output file
+		if err := opts.writeFile(outputPath, []byte(summary), 0644); err != nil {
+			opts.warn(file.Name(), fmt.Errorf("failed to write summary for %s: %w", file.Name(), err))
+		} else {
+			opts.saved(file.Name(), outputPath)
+		}
+		// The store keeps summaries under the real candidate, so blind ones are not stored
+		if opts.Blind == nil {
+			opts.storeSummary(baseName, summary)
+		}
+		result.Summaries = append(result.Summaries, Summary{
+			Key:         baseName,
+			CandidateID: candidateID,
+			Path:        outputPath,
+			Text:        summary,
+		})
+	}
+	return result, nil
+}
@@ -0,0 +1,60 @@
+package pipeline
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nicoalimin/resume-analyzer/modules/store/sqlite"
+)
+
+func TestSummarizeStoresOnlyNamedSummaries(t *testing.T) {
+	dir := t.TempDir()
+	// Summarize writes into existing folders
+	writeFiles(t, dir, map[string]string{"txts/jane_doe.txt": sourceText, "blind/.keep": "", "summaries/.keep": ""})
+	store, err := sqlite.NewSQLiteStore(filepath.Join(dir, "candidates.db"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	blind, err := LoadBlindMapping(nil, filepath.Join(dir, "blind-map.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	llm := &fakeLLM{respond: func(string) string { return "Jane Doe is a senior engineer at Acme." }}
+	opts := SummarizeOptions{
+		Env:       Env{Store: store},
+		LLM:       llm,
+		InputDir:  filepath.Join(dir, "txts"),
+		OutputDir: filepath.Join(dir, "blind"),
+		Blind:     blind,
+	}
+	result, err := Summarize(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Summaries) != 1 || strings.Contains(result.Summaries[0].Text, "Jane") {
+		t.Fatalf("summaries = %+v, want one blind summary", result.Summaries)
+	}
+	stored, err := store.ListCandidates("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored) != 0 {
+		t.Errorf("stored %+v, want the blind summary kept out of the store", stored)
+	}
+
+	opts.Blind = nil
+	opts.OutputDir = filepath.Join(dir, "summaries")
+	if _, err := Summarize(opts); err != nil {
+		t.Fatal(err)
+	}
+	stored, err = store.ListCandidates("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored) != 1 || stored[0].Key != "jane_doe" || !strings.HasPrefix(stored[0].Summary, "Jane Doe") {
+		t.Errorf("stored %+v, want the named summary of jane_doe", stored)
+	}
+}
@@ -0,0 +1,119 @@
+package pipeline
+
+import (
+	"strings"
+	"unicode"
+)
+
+// fieldCheck describes a single extracted value to look for in the source text
+type fieldCheck struct {
+	label     string
+	value     string
+	threshold float64
+}
+
+// verifyApplicantInfo checks the extracted values against the original OCR text
+// and returns a description of every value that could not be found in it
+func verifyApplicantInfo(applicant ApplicantInfo, sourceText string) []string {
+	sourceTokens := Tokenize(sourceText)
+	sourceSet := make(map[string]bool, len(sourceTokens))
+	for _, token := range sourceTokens {
+		sourceSet[token] = true
+	}
+
+	// Names must match completely, company and position only partially since
+	// suffixes like "Inc." or abbreviations like "Sr." are often rewritten
+	checks := []fieldCheck{
+		{label: "name", value: applicant.Name, threshold: 1.0},
+		{label: "current_company", value: applicant.CurrentCompany, threshold: 0.5},
+		{label: "current_position", value: applicant.CurrentPosition, threshold: 0.5},
+	}
+	for _, skill := range strings.Split(applicant.Skillset, ",") {
+		checks = append(checks, fieldCheck{label: "skill", value: skill, threshold: 1.0})
+	}
+
+	var unsupported []string
+	for _, check := range checks {
+		value := strings.TrimSpace(check.value)
+		if value == "" || strings.EqualFold(value, "N/A") {
+			continue
+		}
+		if !isSupported(value, sourceSet, check.threshold) {
+			unsupported = append(unsupported, check.label+": "+value)
+		}
+	}
+	return unsupported
+}
+
+// isSupported reports whether enough tokens of value fuzzily appear in the source tokens
+func isSupported(value string, sourceSet map[string]bool, threshold float64) bool {
+	var total, found int
+	for _, token := range Tokenize(value) {
+		// Single letters are usually initials or separators and carry no signal
+		if len([]rune(token)) < 2 {
+			continue
+		}
+		total++
+		if hasFuzzyToken(token, sourceSet) {
+			found++
+		}
+	}
+	if total == 0 {
+		return true
+	}
+	return float64(found)/float64(total) >= threshold
+}
+
+// hasFuzzyToken reports whether token, or a close spelling of it, is in the set.
+// OCR output regularly drops or swaps characters in longer words, so a small
+// edit distance is tolerated depending on the token length.
+func hasFuzzyToken(token string, set map[string]bool) bool {
+	if set[token] {
+		return true
+	}
+	maxDistance := 0
+	switch n := len([]rune(token)); {
+	case n >= 9:
+		maxDistance = 2
+	case n >= 5:
+		maxDistance = 1
+	}
+	if maxDistance == 0 {
+		return false
+	}
+	for candidate := range set {
+		if levenshtein(token, candidate) <= maxDistance {
+			return true
+		}
+	}
+	return false
+}
+
+// Tokenize lowercases the text and splits it on everything that is not a letter or digit
+func Tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// levenshtein returns the edit distance between two strings
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
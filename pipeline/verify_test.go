@@ -0,0 +1,73 @@
+package pipeline
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestVerifyApplicantInfo(t *testing.T) {
+	source := `JANE DOE
+Senior Software Engineer at Acme Corporation Pte. Ltd.
+Skills: Go, Kubernetes, PostgreSQL, Terraform`
+
+	tests := []struct {
+		name      string
+		applicant ApplicantInfo
+		want      []string
+	}{
+		{
+			name: "all supported",
+			applicant: ApplicantInfo{
+				Name:            "Jane Doe",
+				CurrentCompany:  "Acme Corp",
+				CurrentPosition: "Sr. Software Engineer",
+				Skillset:        "Go, Kubernetes, PostgreSQL",
+			},
+		},
+		{
+			name: "OCR typos are tolerated",
+			applicant: ApplicantInfo{
+				Name:     "Jane Doe",
+				Skillset: "Kubernets, Postgresql, Terraform",
+			},
+		},
+		{
+			name: "unsupported values",
+			applicant: ApplicantInfo{
+				Name:           "Jane Smith",
+				CurrentCompany: "Globex",
+				Skillset:       "Go, Rust",
+			},
+			want: []string{"name: Jane Smith", "current_company: Globex", "skill: Rust"},
+		},
+		{
+			name:      "placeholders are skipped",
+			applicant: ApplicantInfo{Name: "N/A", CurrentCompany: "n/a"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := verifyApplicantInfo(test.applicant, source)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("verifyApplicantInfo() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"kubernetes", "kubernets", 1},
+		{"go", "go", 0},
+		{"", "abc", 3},
+		{"résumé", "resume", 2},
+	}
+	for _, test := range tests {
+		if got := levenshtein(test.a, test.b); got != test.want {
+			t.Errorf("levenshtein(%q, %q) = %d, want %d", test.a, test.b, got, test.want)
+		}
+	}
+}
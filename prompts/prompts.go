@@ -36,6 +36,49 @@ Please specifically identify and highlight these skills if present:
 Please provide a structured summary that captures all the above information clearly.`
 }
 
+// GetBlindSummaryPrompt returns the summarization prompt for blind review, which leaves out
+// every attribute that could reveal the applicant's identity or invite bias
+func GetBlindSummaryPrompt(text string, candidateID string) string {
+	return `Please provide a comprehensive summary of the following resume for a blind review. Focus on extracting key information for recruitment purposes:
+
+**Key Information to Extract:**
+1. **Candidate ID**: ` + candidateID + `
+2. **Current Role/Position**: Current job title
+3. **Current Company**: Current employer
+4. **Years of Experience**: Total years of professional experience
+5. **Seniority Level**: Assess as Junior/Mid/Senior/Lead/Manager/Director/VP/C-Level based on:
+   - Years of experience
+   - Scope of responsibilities
+   - Team size managed
+   - Technical complexity handled
+   - Leadership indicators
+
+**Technical Skills Assessment:**
+Please specifically identify and highlight these skills if present:
+- **Frontend**: TypeScript, JavaScript, React, Vue, Angular, Next.js
+- **Backend**: Python, Golang
+- **AI/ML**: AI, LLM, Machine Learning
+- **Cloud**: AWS, GCP, Azure, Alibaba Cloud
+- **DevOps**: Terraform, CI/CD, Docker, Kubernetes
+
+**Additional Information:**
+- **Status**: Active/Passive/Open to opportunities
+- **Key Achievements**: Notable accomplishments
+- **Education**: Degree and field of study only
+- **Remarks**: Any special notes or observations
+
+**Blind Review Rules:**
+- Refer to the applicant only as "` + candidateID + `" or "the candidate", never by name
+- Do not use gendered pronouns or titles; use "they"
+- Do not mention age, date of birth, photos, nationality, citizenship, marital status, religion or family
+- Do not name schools or universities
+
+**Resume Content:**
+` + text + `
+
+Please provide a structured summary that captures all the above information clearly.`
+}
+
 // GetExtractionPrompt returns the prompt for extracting structured information from summaries
 func GetExtractionPrompt(summary string) string {
 	return `Extract the following information from this resume summary and return ONLY a JSON object with these exact keys (use "N/A" if not found):
@@ -58,3 +101,54 @@ Resume Summary:
 
 JSON:`
 }
+
+// GetDirectExtractionPrompt returns the prompt for extracting structured information straight from the raw resume text
+func GetDirectExtractionPrompt(text string) string {
+	return `Extract the following information from this resume and return ONLY a JSON object with these exact keys (use "N/A" if not found).
+Only use information that is explicitly stated in the resume; do not guess employers, titles or skills.
+
+{
+  "name": "Full Name",
+  "role": "Job Role/Title",
+  "seniority": "Junior/Mid/Senior/Lead/Manager/Director/VP/C-Level",
+  "status": "Active/Passive/Open to opportunities",
+  "current_position": "Current Job Title",
+  "current_company": "Current Company Name",
+  "years_of_exp": "X years",
+  "cv_link": "N/A",
+  "skillset": "Key skills separated by commas",
+  "remarks": "Brief notes or observations",
+  "email": "Email address",
+  "phone": "Phone number including country code if given",
+  "location": "City, Country",
+  "linkedin": "LinkedIn profile URL",
+  "github": "GitHub profile URL"
+}
+
+Seniority should be assessed from the years of experience, scope of responsibilities, team size managed,
+technical complexity handled and leadership indicators.
+
+Resume Content:
+` + text + `
+
+JSON:`
+}
+
+// GetContactExtractionPrompt returns the prompt for extracting contact details from the raw resume text
+func GetContactExtractionPrompt(text string) string {
+	return `Extract the applicant's contact details from this resume and return ONLY a JSON object with these exact keys (use "N/A" if not found).
+Copy the values exactly as they appear in the resume; do not invent or complete them.
+
+{
+  "email": "Email address",
+  "phone": "Phone number including country code if given",
+  "location": "City, Country",
+  "linkedin": "LinkedIn profile URL",
+  "github": "GitHub profile URL"
+}
+
+Resume Content:
+` + text + `
+
+JSON:`
+}
@@ -0,0 +1,71 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/nicoalimin/resume-analyzer/interfaces"
+)
+
+// HeaderAPIKey is an alternative to "Authorization: Bearer <key>" for sending the API key
+const HeaderAPIKey = "X-API-Key"
+
+// teamKey is the context key of the team of an authenticated request
+type teamKey struct{}
+
+// needsKey reports whether a request must be authenticated: every API route except the
+// health check, if keys are configured. The web UI asks for the key itself.
+func (s *Server) needsKey(r *http.Request) bool {
+	return s.opts.Keys != nil && strings.HasPrefix(r.URL.Path, "/api/") && r.URL.Path != "/api/health"
+}
+
+// authenticate checks the API key of a request and returns the request with the team of the
+// key in its context. An error response is written if the key is missing or invalid.
+func (s *Server) authenticate(w http.ResponseWriter, r *http.Request) (*http.Request, bool) {
+	secret := requestKey(r)
+	if secret == "" {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeError(w, http.StatusUnauthorized, "unauthorized", "an API key is required")
+		return nil, false
+	}
+	key, err := s.opts.Keys.Authenticate(secret)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal", err.Error())
+		return nil, false
+	}
+	if key == nil {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeError(w, http.StatusUnauthorized, "unauthorized", "invalid or revoked API key")
+		return nil, false
+	}
+	return r.WithContext(context.WithValue(r.Context(), teamKey{}, key.Team)), true
+}
+
+// requestKey returns the API key sent with a request, or an empty string
+func requestKey(r *http.Request) string {
+	if key := r.Header.Get(HeaderAPIKey); key != "" {
+		return key
+	}
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// teamContext returns a context whose LLM calls are made for a team, so the response cache
+// keeps the responses of different teams apart
+func teamContext(ctx context.Context, team string) context.Context {
+	if team == "" {
+		return ctx
+	}
+	return interfaces.WithCall(ctx, interfaces.Call{Team: team})
+}
+
+// teamOf returns the team of an authenticated request. It is empty without authentication,
+// which gives access to the jobs of every team.
+func teamOf(r *http.Request) string {
+	team, _ := r.Context().Value(teamKey{}).(string)
+	return team
+}
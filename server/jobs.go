@@ -0,0 +1,223 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/nicoalimin/resume-analyzer/analyzer"
+	"github.com/nicoalimin/resume-analyzer/interfaces"
+	"github.com/nicoalimin/resume-analyzer/modules/webhook"
+	"github.com/nicoalimin/resume-analyzer/pipeline"
+)
+
+// pollInterval is how often idle workers look for tasks that became due, e.g. retries
+const pollInterval = 2 * time.Second
+
+// workers analyze the tasks of the job queue
+type workers struct {
+	server *Server
+	wake   chan struct{}
+	stop   context.CancelFunc
+	done   sync.WaitGroup
+
+	mu sync.Mutex
+	// running holds the cancel functions of the running tasks by job ID
+	running map[string]map[int64]context.CancelFunc
+}
+
+func startWorkers(s *Server, count int) *workers {
+	ctx, stop := context.WithCancel(context.Background())
+	w := &workers{
+		server:  s,
+		wake:    make(chan struct{}, 1),
+		stop:    stop,
+		running: map[string]map[int64]context.CancelFunc{},
+	}
+	for i := 0; i < count; i++ {
+		w.done.Add(1)
+		go w.work(ctx)
+	}
+	return w
+}
+
+// notify wakes up an idle worker after a job was submitted
+func (w *workers) notify() {
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+// cancel stops the running tasks of a job at the next step
+func (w *workers) cancel(jobID string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, cancel := range w.running[jobID] {
+		cancel()
+	}
+}
+
+// close stops claiming tasks and waits for the running ones to finish
+func (w *workers) close() {
+	w.stop()
+	w.done.Wait()
+}
+
+func (w *workers) work(ctx context.Context) {
+	defer w.done.Done()
+	for ctx.Err() == nil {
+		task, err := w.server.opts.Queue.Claim()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Job queue: %v\n", err)
+		}
+		if task == nil {
+			select {
+			case <-w.wake:
+			case <-time.After(pollInterval):
+			case <-ctx.Done():
+			}
+			continue
+		}
+		w.run(task)
+		// Other workers may be idle while more tasks are queued
+		w.notify()
+	}
+}
+
+func (w *workers) run(task *interfaces.JobTask) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	w.track(task, cancel)
+	defer w.untrack(task)
+
+	queue := w.server.opts.Queue
+	result, err := w.server.analyze(ctx, task.Team, task.Name, bytes.NewReader(task.Content))
+	switch {
+	case err == nil:
+		var data []byte
+		if data, err = json.Marshal(result); err == nil {
+			err = queue.Complete(task.ID, data)
+		}
+		if err == nil {
+			w.server.notify(task.Team, webhook.EventCandidateSucceeded, candidateEvent{
+				JobID: task.JobID, Team: task.Team, File: task.Name, Attempts: task.Attempts, Profile: result.Profile,
+			})
+		}
+	case ctx.Err() != nil:
+		// The job was canceled and the queue has already marked the task
+		return
+	case task.Attempts < w.server.opts.MaxAttempts:
+		at := time.Now().Add(time.Duration(task.Attempts) * w.server.opts.RetryDelay)
+		err = queue.Retry(task.ID, err.Error(), at)
+	default:
+		reason := err.Error()
+		if err = queue.Fail(task.ID, reason); err == nil {
+			w.server.notify(task.Team, webhook.EventCandidateFailed, candidateEvent{
+				JobID: task.JobID, Team: task.Team, File: task.Name, Attempts: task.Attempts, Error: reason,
+			})
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Job queue: %v\n", err)
+	}
+	w.server.finishJob(task.JobID)
+}
+
+func (w *workers) track(task *interfaces.JobTask, cancel context.CancelFunc) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.running[task.JobID] == nil {
+		w.running[task.JobID] = map[int64]context.CancelFunc{}
+	}
+	w.running[task.JobID][task.ID] = cancel
+}
+
+func (w *workers) untrack(task *interfaces.JobTask) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	delete(w.running[task.JobID], task.ID)
+	if len(w.running[task.JobID]) == 0 {
+		delete(w.running, task.JobID)
+	}
+}
+
+// candidateEvent is the data of the candidate.succeeded and candidate.failed events
+type candidateEvent struct {
+	JobID    string            `json:"job_id"`
+	Team     string            `json:"team,omitempty"`
+	File     string            `json:"file"`
+	Attempts int               `json:"attempts"`
+	Error    string            `json:"error,omitempty"`
+	Profile  *analyzer.Profile `json:"profile,omitempty"`
+}
+
+// notify sends an event of a team if a notifier is configured
+func (s *Server) notify(team string, eventType string, data any) {
+	if s.opts.Notifier != nil {
+		s.opts.Notifier.Send(team, eventType, data)
+	}
+}
+
+// finishJob sends the job.succeeded, job.failed or job.canceled event once every file of
+// the job is finished. The results are left out; they can be fetched from the API.
+func (s *Server) finishJob(id string) {
+	if s.opts.Notifier == nil {
+		return
+	}
+	job, err := s.opts.Queue.ClaimFinished(id)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Job queue: %v\n", err)
+		return
+	}
+	if job == nil {
+		return
+	}
+	for i := range job.Files {
+		job.Files[i].Result = nil
+	}
+	eventType := webhook.EventJobSucceeded
+	switch job.State {
+	case interfaces.JobFailed:
+		eventType = webhook.EventJobFailed
+	case interfaces.JobCanceled:
+		eventType = webhook.EventJobCanceled
+	}
+	s.notify(job.Team, eventType, job)
+}
+
+// jobDocuments returns the texts of the files of a job that were analyzed successfully
+func jobDocuments(job interfaces.Job) []pipeline.QueryDocument {
+	var documents []pipeline.QueryDocument
+	for _, file := range job.Files {
+		if file.State != interfaces.JobSucceeded {
+			continue
+		}
+		var result Result
+		if err := json.Unmarshal(file.Result, &result); err != nil {
+			continue
+		}
+		documents = append(documents, pipeline.QueryDocument{Name: file.Name, Text: result.Text})
+	}
+	return documents
+}
+
+// jobCandidates returns the profiles of the files of a job that were analyzed successfully
+func jobCandidates(job interfaces.Job) []candidate {
+	var candidates []candidate
+	for _, file := range job.Files {
+		if file.State != interfaces.JobSucceeded {
+			continue
+		}
+		var result Result
+		if err := json.Unmarshal(file.Result, &result); err != nil || result.Profile == nil {
+			continue
+		}
+		candidates = append(candidates, candidate{JobID: job.ID, File: file.Name, Profile: result.Profile})
+	}
+	return candidates
+}
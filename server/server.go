@@ -0,0 +1,417 @@
+// Package server exposes the resume analyzer as a REST API. Clients upload PDFs and get
+// back the OCR text, summary and structured fields, either synchronously or as batch jobs
+// that are processed in the background from a durable job queue, and can ask questions
+// about the analyzed resumes. A web UI built on the API is served at /.
+//
+// With a key store, every API request must send an API key, as "Authorization: Bearer <key>"
+// or in the X-API-Key header. Each key belongs to a team, and only sees the jobs, candidates
+// and query results of its team.
+//
+// Every error is returned as JSON:
+//
+//	{"error": {"code": "too_large", "message": "request body exceeds 10485760 bytes"}}
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/nicoalimin/resume-analyzer/analyzer"
+	"github.com/nicoalimin/resume-analyzer/interfaces"
+	"github.com/nicoalimin/resume-analyzer/modules/llm/usage"
+	"github.com/nicoalimin/resume-analyzer/pipeline"
+)
+
+const (
+	defaultMaxUploadSize = 10 << 20
+	defaultMaxBatchFiles = 50
+	defaultWorkers       = 2
+	defaultMaxAttempts   = 3
+	defaultRetryDelay    = 30 * time.Second
+)
+
+// Options configures a Server
+type Options struct {
+	// OCR extracts the text from uploaded PDFs
+	OCR interfaces.OCRService
+	// LLM writes the summaries, extracts the structured fields and answers queries
+	LLM interfaces.LLMService
+	// PhoneCountryCode is used to convert phone numbers without an international prefix to E.164
+	PhoneCountryCode string
+	// ContactFallback asks the LLM for contact details that pattern matching did not find
+	ContactFallback bool
+	// MaxUploadSize is the maximum size of a request body in bytes (default 10 MB)
+	MaxUploadSize int64
+	// MaxBatchFiles is the maximum number of PDFs in one batch job (default 50)
+	MaxBatchFiles int
+	// Queue stores the batch jobs
+	Queue interfaces.JobQueue
+	// Workers is the number of batch files analyzed at the same time (default 2)
+	Workers int
+	// MaxAttempts is how often a batch file is analyzed before it is marked as failed (default 3)
+	MaxAttempts int
+	// RetryDelay is the wait before the first retry of a failed file; later retries wait
+	// correspondingly longer (default 30s)
+	RetryDelay time.Duration
+	// Notifier, if set, is sent an event when a file of a batch job succeeds or fails for the
+	// last time, and when every file of a job is finished or the job is canceled
+	Notifier interfaces.Notifier
+	// DisableUI turns off the web UI; only the API is served
+	DisableUI bool
+	// Keys, if set, authenticates the API requests and scopes them to the team of their key
+	Keys interfaces.KeyStore
+}
+
+// Result is the analysis of one resume
+type Result struct {
+	File    string            `json:"file"`
+	Text    string            `json:"text"`
+	Profile *analyzer.Profile `json:"profile"`
+}
+
+// Server handles the API requests
+type Server struct {
+	opts     Options
+	analyzer *analyzer.Analyzer
+	workers  *workers
+	mux      *http.ServeMux
+}
+
+// New returns a Server using the given options and starts its workers. Tasks left running
+// in the queue by a previous process should be requeued before.
+func New(opts Options) *Server {
+	if opts.MaxUploadSize <= 0 {
+		opts.MaxUploadSize = defaultMaxUploadSize
+	}
+	if opts.MaxBatchFiles <= 0 {
+		opts.MaxBatchFiles = defaultMaxBatchFiles
+	}
+	if opts.Workers <= 0 {
+		opts.Workers = defaultWorkers
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = defaultMaxAttempts
+	}
+	if opts.RetryDelay <= 0 {
+		opts.RetryDelay = defaultRetryDelay
+	}
+
+	s := &Server{
+		opts: opts,
+		analyzer: analyzer.New(analyzer.Options{
+			OCR:              opts.OCR,
+			LLM:              opts.LLM,
+			PhoneCountryCode: opts.PhoneCountryCode,
+			ContactFallback:  opts.ContactFallback,
+			Summarize:        true,
+		}),
+		mux: http.NewServeMux(),
+	}
+
+	s.mux.HandleFunc("GET /api/health", s.handleHealth)
+	s.mux.HandleFunc("POST /api/analyze", s.handleAnalyze)
+	s.mux.HandleFunc("POST /api/jobs", s.handleCreateJob)
+	s.mux.HandleFunc("GET /api/jobs", s.handleListJobs)
+	s.mux.HandleFunc("GET /api/jobs/{id}", s.handleGetJob)
+	s.mux.HandleFunc("POST /api/jobs/{id}/cancel", s.handleCancelJob)
+	s.mux.HandleFunc("GET /api/candidates", s.handleListCandidates)
+	s.mux.HandleFunc("POST /api/query", s.handleQuery)
+	if !opts.DisableUI {
+		index, assets := uiHandler()
+		s.mux.HandleFunc("GET /{$}", index)
+		s.mux.Handle("GET /ui/", assets)
+	}
+	s.mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("no route for %s %s", r.Method, r.URL.Path))
+	})
+	s.workers = startWorkers(s, opts.Workers)
+	return s
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, s.opts.MaxUploadSize)
+	if s.needsKey(r) {
+		var ok bool
+		if r, ok = s.authenticate(w, r); !ok {
+			return
+		}
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// Close stops the workers after the files they are analyzing are done. Queued files stay
+// in the queue.
+func (s *Server) Close() {
+	s.workers.close()
+}
+
+// analyze runs the analysis of one PDF
+func (s *Server) analyze(ctx context.Context, team string, name string, pdf io.Reader) (*Result, error) {
+	profile, err := s.analyzer.AnalyzeFile(teamContext(ctx, team), name, pdf)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{File: name, Text: profile.Text, Profile: profile}, nil
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// handleAnalyze analyzes a single PDF sent as the "file" field of a multipart form or as the
+// request body with Content-Type application/pdf, and returns the Result
+func (s *Server) handleAnalyze(w http.ResponseWriter, r *http.Request) {
+	var name string
+	var pdf io.Reader
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/pdf") {
+		name, pdf = r.URL.Query().Get("name"), r.Body
+	} else {
+		files, ok := s.uploadedFiles(w, r, "file")
+		if !ok {
+			return
+		}
+		if len(files) != 1 {
+			writeError(w, http.StatusBadRequest, "bad_request", "exactly one file must be uploaded in the \"file\" field")
+			return
+		}
+		file, err := files[0].Open()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "internal", err.Error())
+			return
+		}
+		defer file.Close()
+		name, pdf = files[0].Filename, file
+	}
+
+	result, err := s.analyze(r.Context(), teamOf(r), name, pdf)
+	if err != nil {
+		if isTooLarge(err) {
+			s.writeTooLarge(w)
+			return
+		}
+		if errors.Is(err, usage.ErrBudgetExceeded) {
+			writeError(w, http.StatusTooManyRequests, "budget_exceeded", err.Error())
+			return
+		}
+		writeError(w, http.StatusBadGateway, "analysis_failed", err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// handleCreateJob queues the PDFs sent in the "files" field of a multipart form as a batch job
+func (s *Server) handleCreateJob(w http.ResponseWriter, r *http.Request) {
+	files, ok := s.uploadedFiles(w, r, "files")
+	if !ok {
+		return
+	}
+	if len(files) == 0 {
+		writeError(w, http.StatusBadRequest, "bad_request", "no files uploaded in the \"files\" field")
+		return
+	}
+	if len(files) > s.opts.MaxBatchFiles {
+		writeError(w, http.StatusBadRequest, "too_many_files", fmt.Sprintf("a batch may contain at most %d files", s.opts.MaxBatchFiles))
+		return
+	}
+
+	uploads := make([]interfaces.JobUpload, 0, len(files))
+	for _, header := range files {
+		file, err := header.Open()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "internal", err.Error())
+			return
+		}
+		content, err := io.ReadAll(file)
+		file.Close()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "internal", err.Error())
+			return
+		}
+		uploads = append(uploads, interfaces.JobUpload{Name: header.Filename, Content: content})
+	}
+
+	job, err := s.opts.Queue.Submit(teamOf(r), uploads)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal", err.Error())
+		return
+	}
+	s.workers.notify()
+	w.Header().Set("Location", "/api/jobs/"+job.ID)
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+func (s *Server) handleListJobs(w http.ResponseWriter, r *http.Request) {
+	jobs, err := s.opts.Queue.List(teamOf(r))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal", err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"jobs": jobs})
+}
+
+func (s *Server) handleGetJob(w http.ResponseWriter, r *http.Request) {
+	job, err := s.opts.Queue.Get(teamOf(r), r.PathValue("id"))
+	if err != nil {
+		writeJobError(w, r.PathValue("id"), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
+
+// handleCancelJob cancels the files of a job that have not finished yet
+func (s *Server) handleCancelJob(w http.ResponseWriter, r *http.Request) {
+	job, err := s.opts.Queue.Cancel(teamOf(r), r.PathValue("id"))
+	if err != nil {
+		writeJobError(w, r.PathValue("id"), err)
+		return
+	}
+	s.workers.cancel(job.ID)
+	s.finishJob(job.ID)
+	writeJSON(w, http.StatusOK, job)
+}
+
+// candidate is a resume of a job that was analyzed successfully
+type candidate struct {
+	JobID   string            `json:"job_id"`
+	File    string            `json:"file"`
+	Profile *analyzer.Profile `json:"profile"`
+}
+
+// handleListCandidates returns the profiles of every analyzed resume, newest job first,
+// without the resume texts
+func (s *Server) handleListCandidates(w http.ResponseWriter, r *http.Request) {
+	jobs, err := s.opts.Queue.List(teamOf(r))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal", err.Error())
+		return
+	}
+	candidates := []candidate{}
+	for _, job := range jobs {
+		candidates = append(candidates, jobCandidates(job)...)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"candidates": candidates})
+}
+
+// queryRequest asks a question about the resumes of one job, or of every job if JobID is empty
+type queryRequest struct {
+	Prompt string `json:"prompt"`
+	JobID  string `json:"job_id"`
+}
+
+func (s *Server) handleQuery(w http.ResponseWriter, r *http.Request) {
+	var request queryRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		if isTooLarge(err) {
+			s.writeTooLarge(w)
+			return
+		}
+		writeError(w, http.StatusBadRequest, "bad_request", fmt.Sprintf("invalid JSON body: %v", err))
+		return
+	}
+	if strings.TrimSpace(request.Prompt) == "" {
+		writeError(w, http.StatusBadRequest, "bad_request", "a prompt must be specified")
+		return
+	}
+
+	var documents []pipeline.QueryDocument
+	if request.JobID != "" {
+		job, err := s.opts.Queue.Get(teamOf(r), request.JobID)
+		if err != nil {
+			writeJobError(w, request.JobID, err)
+			return
+		}
+		documents = jobDocuments(job)
+	} else {
+		jobs, err := s.opts.Queue.List(teamOf(r))
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "internal", err.Error())
+			return
+		}
+		for _, job := range jobs {
+			documents = append(documents, jobDocuments(job)...)
+		}
+	}
+	if len(documents) == 0 {
+		writeError(w, http.StatusConflict, "no_resumes", "no analyzed resumes to query yet")
+		return
+	}
+
+	result, err := pipeline.Query(pipeline.QueryOptions{
+		Env:       pipeline.Env{Context: teamContext(r.Context(), teamOf(r))},
+		LLM:       s.opts.LLM,
+		Prompt:    request.Prompt,
+		Documents: documents,
+	})
+	if errors.Is(err, usage.ErrBudgetExceeded) {
+		writeError(w, http.StatusTooManyRequests, "budget_exceeded", err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusBadGateway, "query_failed", err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"response": result.Response, "files": result.Files})
+}
+
+// uploadedFiles parses a multipart form and returns the files in field. An error response
+// is written if the form cannot be parsed.
+func (s *Server) uploadedFiles(w http.ResponseWriter, r *http.Request, field string) ([]*multipart.FileHeader, bool) {
+	if err := r.ParseMultipartForm(s.opts.MaxUploadSize); err != nil {
+		if isTooLarge(err) {
+			s.writeTooLarge(w)
+			return nil, false
+		}
+		writeError(w, http.StatusBadRequest, "bad_request", fmt.Sprintf("expected a multipart form: %v", err))
+		return nil, false
+	}
+	return r.MultipartForm.File[field], true
+}
+
+func isTooLarge(err error) bool {
+	var maxBytesErr *http.MaxBytesError
+	return errors.As(err, &maxBytesErr)
+}
+
+func (s *Server) writeTooLarge(w http.ResponseWriter) {
+	writeError(w, http.StatusRequestEntityTooLarge, "too_large", fmt.Sprintf("request body exceeds %d bytes", s.opts.MaxUploadSize))
+}
+
+// writeJobError writes the response for an error returned by the job queue
+func writeJobError(w http.ResponseWriter, id string, err error) {
+	if errors.Is(err, interfaces.ErrJobNotFound) {
+		writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("job %s not found", id))
+		return
+	}
+	writeError(w, http.StatusInternalServerError, "internal", err.Error())
+}
+
+// apiError is the body of every error response
+type apiError struct {
+	Error struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func writeError(w http.ResponseWriter, status int, code string, message string) {
+	var body apiError
+	body.Error.Code = code
+	body.Error.Message = message
+	writeJSON(w, status, body)
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(body)
+}
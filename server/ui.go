@@ -0,0 +1,26 @@
+package server
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+// uiFiles is the web UI. It is plain HTML, CSS and JavaScript that only uses the API, so
+// there is nothing to build.
+//
+//go:embed ui
+var uiFiles embed.FS
+
+// uiHandler serves the web UI: index.html at / and its assets under /ui/
+func uiHandler() (index http.HandlerFunc, assets http.Handler) {
+	root, err := fs.Sub(uiFiles, "ui")
+	if err != nil {
+		panic(err)
+	}
+	index = func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-cache")
+		http.ServeFileFS(w, r, root, "index.html")
+	}
+	return index, http.StripPrefix("/ui/", http.FileServerFS(root))
+}
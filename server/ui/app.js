@@ -0,0 +1,422 @@
+(function () {
+  "use strict";
+
+  var pollInterval = 2000;
+  var shownJobs = 10;
+  var keyStorage = "resume-analyzer-api-key";
+
+  var state = {
+    jobs: [],
+    candidates: [],
+    succeeded: -1,
+    sortKey: "name",
+    sortAsc: true,
+    polling: null
+  };
+
+  function $(id) { return document.getElementById(id); }
+
+  // el creates an element; text is always set as text so resume content cannot inject markup
+  function el(tag, className, text) {
+    var node = document.createElement(tag);
+    if (className) { node.className = className; }
+    if (text !== undefined && text !== null) { node.textContent = text; }
+    return node;
+  }
+
+  function apiKey() {
+    try { return localStorage.getItem(keyStorage) || ""; } catch (e) { return ""; }
+  }
+
+  function api(method, path, body) {
+    var init = { method: method, headers: {} };
+    if (apiKey()) { init.headers.Authorization = "Bearer " + apiKey(); }
+    if (body instanceof FormData) {
+      init.body = body;
+    } else if (body !== undefined) {
+      init.headers["Content-Type"] = "application/json";
+      init.body = JSON.stringify(body);
+    }
+    return fetch(path, init).then(function (response) {
+      return response.json().catch(function () { return {}; }).then(function (data) {
+        if (!response.ok) {
+          var err = new Error(data.error ? data.error.message : response.status + " " + response.statusText);
+          err.status = response.status;
+          if (response.status === 401) { showSignIn(err.message); }
+          throw err;
+        }
+        return data;
+      });
+    });
+  }
+
+  function showError(node, err) {
+    node.textContent = err ? err.message : "";
+    node.hidden = !err;
+  }
+
+  // Sign in
+
+  function showSignIn(message) {
+    $("signin").hidden = false;
+    $("signout").hidden = true;
+    $("signin-error").textContent = apiKey() ? message : "";
+    $("signin-key").focus();
+  }
+
+  function signIn(event) {
+    event.preventDefault();
+    var key = $("signin-key").value.trim();
+    if (!key) { return; }
+    try { localStorage.setItem(keyStorage, key); } catch (e) { /* the key is only kept for this page */ }
+    $("signin-key").value = "";
+    $("signin").hidden = true;
+    $("signout").hidden = false;
+    state.succeeded = -1;
+    showError($("upload-error"), null);
+    loadJobs();
+  }
+
+  function signOut() {
+    try { localStorage.removeItem(keyStorage); } catch (e) { /* nothing stored */ }
+    location.reload();
+  }
+
+  function updateStatus() {
+    var active = state.jobs.filter(isActive).length;
+    var text = state.candidates.length + " candidates · " + state.jobs.length + " jobs";
+    if (active) { text += " · " + active + " in progress"; }
+    $("status").textContent = text;
+  }
+
+  // Jobs
+
+  function isActive(job) { return job.state === "queued" || job.state === "running"; }
+
+  function isFinished(file) { return file.state !== "queued" && file.state !== "running"; }
+
+  function loadJobs() {
+    return api("GET", "/api/jobs").then(function (data) {
+      state.jobs = data.jobs || [];
+      renderJobs();
+      renderJobOptions();
+
+      var succeeded = 0;
+      state.jobs.forEach(function (job) {
+        job.files.forEach(function (file) { if (file.state === "succeeded") { succeeded++; } });
+      });
+      if (succeeded !== state.succeeded) {
+        state.succeeded = succeeded;
+        loadCandidates();
+      }
+      updateStatus();
+      schedulePoll();
+    }).catch(function (err) {
+      if (err.status === 401) { return; }
+      showError($("upload-error"), err);
+      schedulePoll();
+    });
+  }
+
+  function schedulePoll() {
+    clearTimeout(state.polling);
+    state.polling = null;
+    if (state.jobs.some(isActive)) {
+      state.polling = setTimeout(loadJobs, pollInterval);
+    }
+  }
+
+  function renderJobs() {
+    var container = $("jobs");
+    container.textContent = "";
+    state.jobs.slice(0, shownJobs).forEach(function (job) {
+      var finished = job.files.filter(isFinished).length;
+      var node = el("div", "job");
+
+      var head = el("div", "job-head");
+      head.appendChild(el("span", "title", "Job " + job.id + " · " + new Date(job.created_at).toLocaleString()));
+      head.appendChild(el("span", "muted", finished + " of " + job.files.length + " finished"));
+      head.appendChild(el("span", "state " + job.state, job.state));
+      if (isActive(job)) {
+        var cancel = el("button", "secondary", "Cancel");
+        cancel.addEventListener("click", function () {
+          cancel.disabled = true;
+          api("POST", "/api/jobs/" + encodeURIComponent(job.id) + "/cancel").then(loadJobs, function (err) {
+            showError($("upload-error"), err);
+          });
+        });
+        head.appendChild(cancel);
+      }
+      node.appendChild(head);
+
+      var progress = el("div", "progress");
+      var fill = el("div");
+      fill.style.width = (job.files.length ? 100 * finished / job.files.length : 100) + "%";
+      progress.appendChild(fill);
+      node.appendChild(progress);
+
+      var list = el("ul");
+      job.files.forEach(function (file) {
+        var item = el("li");
+        item.appendChild(el("span", "name", file.name));
+        if (file.attempts > 1) { item.appendChild(el("span", "muted", "attempt " + file.attempts)); }
+        var badge = el("span", "state " + file.state, file.state);
+        if (file.error) { badge.title = file.error; }
+        item.appendChild(badge);
+        list.appendChild(item);
+      });
+      node.appendChild(list);
+      container.appendChild(node);
+    });
+  }
+
+  function renderJobOptions() {
+    var select = $("query-job");
+    var selected = select.value;
+    while (select.options.length > 1) { select.remove(1); }
+    state.jobs.forEach(function (job) {
+      var option = el("option", "", "Job " + job.id + " (" + job.files.length + " files)");
+      option.value = job.id;
+      select.appendChild(option);
+    });
+    select.value = selected;
+  }
+
+  function upload(fileList) {
+    var files = Array.prototype.filter.call(fileList, function (file) {
+      return file.type === "application/pdf" || /\.pdf$/i.test(file.name);
+    });
+    if (!files.length) {
+      showError($("upload-error"), new Error("Only PDF files can be analyzed."));
+      return;
+    }
+    var form = new FormData();
+    files.forEach(function (file) { form.append("files", file, file.name); });
+    showError($("upload-error"), null);
+    api("POST", "/api/jobs", form).then(loadJobs, function (err) {
+      showError($("upload-error"), err);
+    });
+  }
+
+  // Candidates
+
+  function loadCandidates() {
+    return api("GET", "/api/candidates").then(function (data) {
+      state.candidates = data.candidates || [];
+      renderSeniorityOptions();
+      renderCandidates();
+      updateStatus();
+    });
+  }
+
+  function renderSeniorityOptions() {
+    var select = $("filter-seniority");
+    var selected = select.value;
+    var seen = {};
+    state.candidates.forEach(function (c) {
+      var value = (c.profile.seniority || "").trim();
+      if (value) { seen[value.toLowerCase()] = value; }
+    });
+    while (select.options.length > 1) { select.remove(1); }
+    Object.keys(seen).sort().forEach(function (key) {
+      var option = el("option", "", seen[key]);
+      option.value = key;
+      select.appendChild(option);
+    });
+    select.value = selected;
+  }
+
+  function splitTerms(value) {
+    return value.split(",").map(function (term) { return term.trim().toLowerCase(); }).filter(Boolean);
+  }
+
+  // matches applies the filters: the seniority must be equal, every skill term must be part of
+  // one of the skills and the search text part of the name, role, position or company
+  function matches(profile) {
+    var seniority = $("filter-seniority").value;
+    if (seniority && (profile.seniority || "").trim().toLowerCase() !== seniority) { return false; }
+
+    var skills = (profile.skills || []).map(function (skill) { return skill.toLowerCase(); });
+    var wanted = splitTerms($("filter-skills").value);
+    for (var i = 0; i < wanted.length; i++) {
+      if (!skills.some(function (skill) { return skill.indexOf(wanted[i]) !== -1; })) { return false; }
+    }
+
+    var text = $("filter-text").value.trim().toLowerCase();
+    if (text) {
+      var haystack = [profile.name, profile.role, profile.current_position, profile.current_company].join(" ").toLowerCase();
+      if (haystack.indexOf(text) === -1) { return false; }
+    }
+    return true;
+  }
+
+  function sortValue(profile, key) {
+    var value = profile[key];
+    if (Array.isArray(value)) { return value.join(", ").toLowerCase(); }
+    if (typeof value === "number") { return value; }
+    return (value || "").toString().toLowerCase();
+  }
+
+  function compare(a, b) {
+    var x = sortValue(a.profile, state.sortKey), y = sortValue(b.profile, state.sortKey);
+    var cmp;
+    if (typeof x === "number" || typeof y === "number") {
+      cmp = (typeof x === "number" ? x : -Infinity) - (typeof y === "number" ? y : -Infinity);
+    } else {
+      cmp = x.localeCompare(y);
+    }
+    return state.sortAsc ? cmp : -cmp;
+  }
+
+  function renderCandidates() {
+    var shown = state.candidates.filter(function (c) { return matches(c.profile); }).sort(compare);
+    var body = $("candidates").tBodies[0];
+    var cards = $("cards");
+    body.textContent = "";
+    cards.textContent = "";
+
+    shown.forEach(function (c) {
+      var card = renderCard(c);
+      var p = c.profile;
+      var row = el("tr");
+      var nameCell = el("td");
+      var link = el("a", "", p.name || c.file);
+      link.addEventListener("click", function () {
+        card.scrollIntoView({ behavior: "smooth", block: "center" });
+        card.classList.add("highlight");
+        setTimeout(function () { card.classList.remove("highlight"); }, 2000);
+      });
+      nameCell.appendChild(link);
+      if (p.unverified && p.unverified.length) {
+        var warn = el("span", "warn", " ⚠");
+        warn.title = p.unverified.join("; ");
+        nameCell.appendChild(warn);
+      }
+      row.appendChild(nameCell);
+      [p.role, p.seniority, p.status, p.current_position, p.current_company,
+        p.years_of_exp === null || p.years_of_exp === undefined ? "" : p.years_of_exp,
+        (p.skills || []).join(", ")].forEach(function (value) {
+        row.appendChild(el("td", "", value));
+      });
+      body.appendChild(row);
+      cards.appendChild(card);
+    });
+
+    $("no-candidates").hidden = state.candidates.length > 0;
+    $("candidates").hidden = state.candidates.length === 0;
+    $("filter-count").textContent = state.candidates.length ? shown.length + " of " + state.candidates.length + " shown" : "";
+  }
+
+  function renderCard(c) {
+    var p = c.profile;
+    var card = el("div", "card");
+    card.appendChild(el("h3", "", p.name || c.file));
+    var subtitle = p.current_position || "";
+    if (p.current_company) { subtitle += (subtitle ? " at " : "") + p.current_company; }
+    card.appendChild(el("p", "subtitle", subtitle || c.file));
+
+    var fields = el("dl");
+    function field(label, value) {
+      if (!value) { return; }
+      fields.appendChild(el("dt", "", label));
+      var dd = value instanceof Node ? el("dd") : el("dd", "", value);
+      if (value instanceof Node) { dd.appendChild(value); }
+      fields.appendChild(dd);
+    }
+    field("Role", p.role);
+    field("Seniority", p.seniority);
+    field("Status", p.status);
+    field("Years of exp", p.years_of_exp === null || p.years_of_exp === undefined ? "" : String(p.years_of_exp));
+    field("Email", p.email);
+    field("Location", p.location);
+    if (p.skills && p.skills.length) {
+      var tags = document.createDocumentFragment();
+      p.skills.forEach(function (skill) { tags.appendChild(el("span", "tag", skill)); });
+      field("Skills", tags);
+    }
+    field("File", c.file);
+    card.appendChild(fields);
+
+    if (p.unverified && p.unverified.length) {
+      card.appendChild(el("p", "warn", "Unverified: " + p.unverified.join("; ")));
+    }
+    if (p.summary) {
+      var details = el("details");
+      details.appendChild(el("summary", "", "Summary"));
+      details.appendChild(el("div", "summary-text", p.summary));
+      card.appendChild(details);
+    }
+    return card;
+  }
+
+  // Queries
+
+  function ask(event) {
+    event.preventDefault();
+    var prompt = $("query-prompt").value.trim();
+    if (!prompt) { return; }
+    var button = event.target.querySelector("button");
+    button.disabled = true;
+    button.textContent = "Thinking…";
+    showError($("query-error"), null);
+    api("POST", "/api/query", { prompt: prompt, job_id: $("query-job").value }).then(function (data) {
+      $("query-response").textContent = data.response;
+      $("query-response").hidden = false;
+      $("query-files").textContent = "Based on " + (data.files || []).length + " resumes";
+      $("query-files").hidden = false;
+    }, function (err) {
+      showError($("query-error"), err);
+    }).then(function () {
+      button.disabled = false;
+      button.textContent = "Ask";
+    });
+  }
+
+  // Wiring
+
+  // Files dropped next to the drop zone would otherwise be opened by the browser
+  ["dragover", "drop"].forEach(function (type) {
+    document.addEventListener(type, function (event) { event.preventDefault(); });
+  });
+
+  var dropzone = $("dropzone");
+  ["dragenter", "dragover"].forEach(function (type) {
+    dropzone.addEventListener(type, function (event) {
+      event.preventDefault();
+      dropzone.classList.add("over");
+    });
+  });
+  ["dragleave", "drop"].forEach(function (type) {
+    dropzone.addEventListener(type, function () { dropzone.classList.remove("over"); });
+  });
+  dropzone.addEventListener("drop", function (event) {
+    event.preventDefault();
+    upload(event.dataTransfer.files);
+  });
+  $("files").addEventListener("change", function (event) {
+    upload(event.target.files);
+    event.target.value = "";
+  });
+
+  $("filter-seniority").addEventListener("change", renderCandidates);
+  $("filter-skills").addEventListener("input", renderCandidates);
+  $("filter-text").addEventListener("input", renderCandidates);
+
+  var headers = $("candidates").querySelectorAll("th");
+  headers.forEach(function (th) {
+    th.addEventListener("click", function () {
+      state.sortAsc = state.sortKey === th.dataset.key ? !state.sortAsc : true;
+      state.sortKey = th.dataset.key;
+      headers.forEach(function (h) { h.classList.remove("asc", "desc"); });
+      th.classList.add(state.sortAsc ? "asc" : "desc");
+      renderCandidates();
+    });
+  });
+
+  $("query").addEventListener("submit", ask);
+  $("signin").addEventListener("submit", signIn);
+  $("signout").addEventListener("click", signOut);
+  $("signout").hidden = !apiKey();
+
+  loadJobs();
+})();